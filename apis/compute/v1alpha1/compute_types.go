@@ -23,14 +23,56 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+
+	apisv1alpha1 "github.com/crossplane/provider-customcomputeprovider/apis/v1alpha1"
 )
 
 // ComputeParameters are the configurable fields of a Compute.
 type ComputeParameters struct {
 	AWSConfig      AWSConfig      `json:"awsConfig"`
 	InstanceConfig InstanceConfig `json:"instanceConfig"`
+
+	// RecreatePolicy decides what happens when the instance is terminated
+	// outside of Crossplane.
+	// +kubebuilder:validation:Enum=Recreate;ReportOnly
+	// +kubebuilder:default=Recreate
+	// +optional
+	RecreatePolicy RecreatePolicy `json:"recreatePolicy,omitempty"`
 }
 
+// RecreatePolicy decides how an instance terminated outside of Crossplane is
+// handled.
+type RecreatePolicy string
+
+const (
+	// RecreatePolicyRecreate launches a new instance in place of the
+	// terminated one.
+	RecreatePolicyRecreate RecreatePolicy = "Recreate"
+
+	// RecreatePolicyReportOnly reports the termination and leaves the
+	// resource unavailable.
+	RecreatePolicyReportOnly RecreatePolicy = "ReportOnly"
+)
+
+// UpdatePolicy decides how the updates found between the spec and the
+// instance are applied.
+type UpdatePolicy string
+
+const (
+	// UpdatePolicyAutomatic applies the updates as soon as they are found.
+	UpdatePolicyAutomatic UpdatePolicy = "Automatic"
+
+	// UpdatePolicyManual applies the update plan once it is approved with the
+	// approval annotation.
+	UpdatePolicyManual UpdatePolicy = "Manual"
+
+	// UpdatePolicyReportOnly reports the update plan and never applies it.
+	UpdatePolicyReportOnly UpdatePolicy = "ReportOnly"
+)
+
+// AnnotationKeyApprovePlan approves the update plan whose hash it holds.
+const AnnotationKeyApprovePlan = "customcomputeprovider.crossplane.io/approve-plan"
+
 type AWSConfig struct {
 	Region string `json:"region"`
 }
@@ -39,13 +81,80 @@ type Storage struct {
 	DeviceName   string `json:"deviceName"`
 	DiskSize     int32  `json:"diskSize"`
 	InstanceDisk string `json:"diskType"`
+
+	// VolumeID pins an existing volume, which is attached to the instance
+	// instead of a new one being created. Pinned volumes outlive the
+	// instance whatever deleteOnTermination says.
+	// +optional
+	VolumeID string `json:"volumeID,omitempty"`
+
+	// IOPS provisioned for io1, io2 and gp3 volumes.
+	// +optional
+	IOPS *int32 `json:"iops,omitempty"`
+
+	// Throughput provisioned for gp3 volumes, in MiB/s.
+	// +optional
+	Throughput *int32 `json:"throughput,omitempty"`
+
+	// KMSKeyID is the KMS key encrypting the volume. The default EBS key is
+	// used when it is not set.
+	// +optional
+	KMSKeyID string `json:"kmsKeyID,omitempty"`
+
+	// SnapshotID is the snapshot the volume is created from.
+	// +optional
+	SnapshotID string `json:"snapshotID,omitempty"`
+
+	// DeleteOnTermination deletes the volume along with the instance.
+	// +kubebuilder:default=true
+	// +optional
+	DeleteOnTermination *bool `json:"deleteOnTermination,omitempty"`
+
+	// Encrypted only applies when the volume is created.
+	// +kubebuilder:default=true
+	// +optional
+	Encrypted *bool `json:"encrypted,omitempty"`
+
+	// AllowReplaceOnShrink lets a smaller diskSize replace the volume, EBS
+	// volumes being unable to shrink. The volume is detached, a snapshot of
+	// it is taken and both are retained, their IDs are recorded in the
+	// storageVolumes of the status. The new volume of the desired size that
+	// takes its place is EMPTY, the data has to be copied back from the
+	// retained volume or the snapshot. It does not apply to the root volume
+	// or to a pinned volume.
+	// +optional
+	AllowReplaceOnShrink bool `json:"allowReplaceOnShrink,omitempty"`
+
+	// RemovalPolicy decides what happens to the volume once it is detached
+	// because its entry was removed from the storage list.
+	// +kubebuilder:validation:Enum=Retain;Snapshot;Delete
+	// +kubebuilder:default=Retain
+	// +optional
+	RemovalPolicy RemovalPolicy `json:"removalPolicy,omitempty"`
 }
 
+// RemovalPolicy is what happens to a volume detached from the instance.
+type RemovalPolicy string
+
+const (
+	// RemovalPolicyRetain keeps the volume.
+	RemovalPolicyRetain RemovalPolicy = "Retain"
+
+	// RemovalPolicySnapshot takes a final snapshot of the volume and deletes
+	// it once the snapshot completed.
+	RemovalPolicySnapshot RemovalPolicy = "Snapshot"
+
+	// RemovalPolicyDelete deletes the volume.
+	RemovalPolicyDelete RemovalPolicy = "Delete"
+)
+
 type Networking struct {
 	SubnetID               string   `json:"subnetID"`
 	InstanceSecurityGroups []string `json:"securityGroups"`
 }
 
+// +kubebuilder:validation:XValidation:rule="!(has(self.keyName) && has(self.keyPairGeneration))",message="keyName and keyPairGeneration are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="!has(self.powerState) || self.powerState != 'Hibernated' || (has(self.hibernationEnabled) && self.hibernationEnabled)",message="powerState Hibernated requires hibernationEnabled"
 type InstanceConfig struct {
 	InstanceName string            `json:"name"`
 	InstanceType string            `json:"type"`
@@ -54,19 +163,421 @@ type InstanceConfig struct {
 
 	Networking Networking `json:"networking"`
 	Storage    []Storage  `json:"storage"`
+
+	// UserData is passed to the instance at boot, e.g. a cloud-init script.
+	// It is given as plain text, the provider encodes it.
+	// +optional
+	UserData string `json:"userData,omitempty"`
+
+	// UserDataSecretRef reads the user data from a key of a Secret, for
+	// scripts that carry credentials.
+	// +optional
+	UserDataSecretRef *xpv1.SecretKeySelector `json:"userDataSecretRef,omitempty"`
+
+	// UserDataConfigMapRef reads the user data from a key of a ConfigMap.
+	// +optional
+	UserDataConfigMapRef *ConfigMapKeySelector `json:"userDataConfigMapRef,omitempty"`
+
+	// UserDataUpdatePolicy decides how a change to the user data is applied
+	// to a running instance. StopStart modifies it while the instance is
+	// stopped, Replace launches a new instance through the replacement policy.
+	// +kubebuilder:validation:Enum=StopStart;Replace
+	// +kubebuilder:default=StopStart
+	// +optional
+	UserDataUpdatePolicy UserDataUpdatePolicy `json:"userDataUpdatePolicy,omitempty"`
+
+	// MarketOptions launches the instance as a Spot instance. Instances are
+	// launched On-Demand when it is not set. It only applies at launch.
+	// +optional
+	MarketOptions *MarketOptions `json:"marketOptions,omitempty"`
+
+	// IAMInstanceProfile is the name or the ARN of the IAM instance profile
+	// attached to the instance. It is swapped on the running instance when it
+	// changes, and detached when removed.
+	// +optional
+	IAMInstanceProfile string `json:"iamInstanceProfile,omitempty"`
+
+	// MetadataOptions configures the instance metadata service. They are
+	// enforced on the running instance, options left unset keep the value
+	// EC2 picked.
+	// +optional
+	MetadataOptions *MetadataOptions `json:"metadataOptions,omitempty"`
+
+	// KeyName is the name of an existing EC2 key pair the instance is
+	// launched with. It is checked against the key pairs of the region
+	// before the launch.
+	// +optional
+	KeyName string `json:"keyName,omitempty"`
+
+	// KeyPairGeneration has the provider generate a key pair, import it into
+	// EC2 and publish its private key in the connection secret. The key pair
+	// is deleted along with the Compute.
+	// +optional
+	KeyPairGeneration *KeyPairGeneration `json:"keyPairGeneration,omitempty"`
+
+	// PowerState is the state the instance is kept in. Stopped and
+	// Hibernated park the instance without deleting it, Hibernated keeps the
+	// memory of the instance on its root volume.
+	// +kubebuilder:validation:Enum=Running;Stopped;Hibernated
+	// +kubebuilder:default=Running
+	// +optional
+	PowerState PowerState `json:"powerState,omitempty"`
+
+	// HibernationEnabled launches the instance with hibernation support,
+	// which requires an encrypted root volume large enough for the memory.
+	// It only applies at launch.
+	// +optional
+	HibernationEnabled bool `json:"hibernationEnabled,omitempty"`
+
+	// SSHUsername is published in the connection secret of instances launched
+	// with a key pair. It depends on the AMI, e.g. ubuntu for Ubuntu images.
+	// +kubebuilder:default=ec2-user
+	// +optional
+	SSHUsername string `json:"sshUsername,omitempty"`
+
+	// ReplacementPolicy controls how the instance is replaced when a change,
+	// such as a new AMI, cannot be applied in place.
+	// +optional
+	ReplacementPolicy ReplacementPolicy `json:"replacementPolicy,omitempty"`
+}
+
+// A ConfigMapKeySelector is a reference to a ConfigMap key in an arbitrary
+// namespace.
+type ConfigMapKeySelector struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Key       string `json:"key"`
+}
+
+// KeyPairType is the algorithm of a generated key pair.
+type KeyPairType string
+
+const (
+	KeyPairTypeED25519 KeyPairType = "ED25519"
+	KeyPairTypeRSA     KeyPairType = "RSA"
+)
+
+type KeyPairGeneration struct {
+	// +kubebuilder:validation:Enum=ED25519;RSA
+	// +kubebuilder:default=ED25519
+	// +optional
+	Type KeyPairType `json:"type,omitempty"`
+}
+
+// PowerState is the state an instance is kept in.
+type PowerState string
+
+const (
+	PowerStateRunning    PowerState = "Running"
+	PowerStateStopped    PowerState = "Stopped"
+	PowerStateHibernated PowerState = "Hibernated"
+)
+
+type MetadataOptions struct {
+	// HTTPTokens set to required enforces IMDSv2, session tokens being
+	// optional otherwise.
+	// +kubebuilder:validation:Enum=optional;required
+	// +optional
+	HTTPTokens string `json:"httpTokens,omitempty"`
+
+	// HTTPPutResponseHopLimit is the number of network hops the metadata
+	// token response can travel.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=64
+	// +optional
+	HTTPPutResponseHopLimit *int32 `json:"httpPutResponseHopLimit,omitempty"`
+
+	// +kubebuilder:validation:Enum=enabled;disabled
+	// +optional
+	HTTPEndpoint string `json:"httpEndpoint,omitempty"`
+
+	// InstanceMetadataTags exposes the instance tags in the metadata.
+	// +kubebuilder:validation:Enum=enabled;disabled
+	// +optional
+	InstanceMetadataTags string `json:"instanceMetadataTags,omitempty"`
+}
+
+// MarketType is the purchasing option of an instance.
+type MarketType string
+
+const (
+	MarketTypeOnDemand MarketType = "OnDemand"
+	MarketTypeSpot     MarketType = "Spot"
+)
+
+// SpotInstanceType tells whether a Spot request is fulfilled once or kept
+// open to restart the instance after an interruption.
+type SpotInstanceType string
+
+const (
+	SpotInstanceTypeOneTime    SpotInstanceType = "OneTime"
+	SpotInstanceTypePersistent SpotInstanceType = "Persistent"
+)
+
+// InterruptionBehavior is what EC2 does with a Spot instance it reclaims.
+type InterruptionBehavior string
+
+const (
+	InterruptionBehaviorTerminate InterruptionBehavior = "Terminate"
+	InterruptionBehaviorStop      InterruptionBehavior = "Stop"
+	InterruptionBehaviorHibernate InterruptionBehavior = "Hibernate"
+)
+
+// InterruptionPolicy decides what the provider does when EC2 terminates a
+// Spot instance.
+type InterruptionPolicy string
+
+const (
+	// InterruptionPolicyRelaunch launches a new instance with the tags and the
+	// surviving volumes of the interrupted one.
+	InterruptionPolicyRelaunch InterruptionPolicy = "Relaunch"
+
+	// InterruptionPolicyReportOnly records the interruption and leaves the
+	// Compute without an instance.
+	InterruptionPolicyReportOnly InterruptionPolicy = "ReportOnly"
+)
+
+// +kubebuilder:validation:XValidation:rule="self.marketType != 'Spot' || (self.spotInstanceType == 'Persistent') == (self.interruptionBehavior != 'Terminate')",message="persistent spot instances must stop or hibernate on interruption, one-time spot instances must terminate"
+type MarketOptions struct {
+	// +kubebuilder:validation:Enum=OnDemand;Spot
+	// +kubebuilder:default=OnDemand
+	// +optional
+	MarketType MarketType `json:"marketType,omitempty"`
+
+	// MaxPrice is the maximum hourly price paid for a Spot instance. It
+	// defaults to the On-Demand price.
+	// +optional
+	MaxPrice string `json:"maxPrice,omitempty"`
+
+	// +kubebuilder:validation:Enum=OneTime;Persistent
+	// +kubebuilder:default=OneTime
+	// +optional
+	SpotInstanceType SpotInstanceType `json:"spotInstanceType,omitempty"`
+
+	// +kubebuilder:validation:Enum=Terminate;Stop;Hibernate
+	// +kubebuilder:default=Terminate
+	// +optional
+	InterruptionBehavior InterruptionBehavior `json:"interruptionBehavior,omitempty"`
+
+	// InterruptionPolicy applies when EC2 terminates the Spot instance.
+	// Stopped and hibernated instances are restarted by EC2 itself.
+	// +kubebuilder:validation:Enum=Relaunch;ReportOnly
+	// +kubebuilder:default=Relaunch
+	// +optional
+	InterruptionPolicy InterruptionPolicy `json:"interruptionPolicy,omitempty"`
+}
+
+// UserDataUpdatePolicy decides how a user data change reaches the instance.
+type UserDataUpdatePolicy string
+
+const (
+	// UserDataUpdatePolicyStopStart stops the instance, modifies its user
+	// data and starts it again.
+	UserDataUpdatePolicyStopStart UserDataUpdatePolicy = "StopStart"
+
+	// UserDataUpdatePolicyReplace replaces the instance with one launched
+	// with the new user data.
+	UserDataUpdatePolicyReplace UserDataUpdatePolicy = "Replace"
+)
+
+// ReplacementStrategy determines the order in which an instance and its
+// replacement are terminated and launched.
+type ReplacementStrategy string
+
+const (
+	// ReplacementStrategyRecreate terminates the current instance before
+	// launching its replacement.
+	ReplacementStrategyRecreate ReplacementStrategy = "Recreate"
+
+	// ReplacementStrategyCreateBeforeDestroy launches the replacement first and
+	// terminates the current instance once the new one is in place.
+	ReplacementStrategyCreateBeforeDestroy ReplacementStrategy = "CreateBeforeDestroy"
+)
+
+type ReplacementPolicy struct {
+	// +kubebuilder:validation:Enum=Recreate;CreateBeforeDestroy
+	// +kubebuilder:default=CreateBeforeDestroy
+	// +optional
+	Strategy ReplacementStrategy `json:"strategy,omitempty"`
+
+	// ReattachVolumes moves the non-root volumes of the current instance to
+	// its replacement instead of provisioning new ones from the storage spec.
+	// +optional
+	ReattachVolumes bool `json:"reattachVolumes,omitempty"`
+}
+
+// StorageVolume is the volume recorded for a storage entry.
+type StorageVolume struct {
+	VolumeID      string        `json:"volumeID"`
+	RemovalPolicy RemovalPolicy `json:"removalPolicy,omitempty"`
+
+	// ReplacedVolumeID is the volume replaced to shrink the entry. It is
+	// retained along with SnapshotID, a snapshot of its data.
+	ReplacedVolumeID string `json:"replacedVolumeID,omitempty"`
+	SnapshotID       string `json:"snapshotID,omitempty"`
+}
+
+// VolumeRemoval tracks a volume detached from the instance until its removal
+// policy has been applied.
+type VolumeRemoval struct {
+	VolumeID      string        `json:"volumeID"`
+	DeviceName    string        `json:"deviceName,omitempty"`
+	RemovalPolicy RemovalPolicy `json:"removalPolicy,omitempty"`
+	SnapshotID    string        `json:"snapshotID,omitempty"`
+
+	// Snapshot takes a snapshot of the volume before the removal policy is
+	// applied, whatever the policy.
+	Snapshot bool `json:"snapshot,omitempty"`
+}
+
+type VolumeAttachment struct {
+	VolumeID   string `json:"volumeID"`
+	DeviceName string `json:"deviceName"`
+}
+
+// Replacement tracks an instance replacement across reconciles.
+type Replacement struct {
+	PreviousInstanceID string              `json:"previousInstanceID"`
+	Strategy           ReplacementStrategy `json:"strategy"`
+	Phase              string              `json:"phase"`
+	Volumes            []VolumeAttachment  `json:"volumes,omitempty"`
+}
+
+// Disruption tells how an update affects the instance.
+type Disruption string
+
+const (
+	// DisruptionNone updates the instance while it keeps running.
+	DisruptionNone Disruption = "None"
+
+	// DisruptionRestart stops the instance and starts it again.
+	DisruptionRestart Disruption = "Restart"
+
+	// DisruptionStop leaves the instance stopped.
+	DisruptionStop Disruption = "Stop"
+
+	// DisruptionReplace replaces the instance with a new one.
+	DisruptionReplace Disruption = "Replace"
+)
+
+// PlannedOperation is an update of the plan.
+type PlannedOperation struct {
+	Type       string     `json:"type"`
+	Disruption Disruption `json:"disruption"`
+	Current    string     `json:"current,omitempty"`
+	Desired    string     `json:"desired,omitempty"`
+}
+
+// UpdatePlan lists the updates waiting to be applied to the instance, in
+// execution order.
+type UpdatePlan struct {
+	// Hash identifies the plan. Setting the approval annotation to it lets a
+	// Manual update policy apply the plan.
+	Hash string `json:"hash"`
+
+	Operations []PlannedOperation `json:"operations"`
+
+	// Disruption is the most disruptive of the operations.
+	Disruption Disruption `json:"disruption"`
+
+	CreatedAt metav1.Time `json:"createdAt"`
+}
+
+// DeferredUpdates are updates waiting on a maintenance window.
+type DeferredUpdates struct {
+	Operations []string `json:"operations"`
+
+	// NextWindow is when the next maintenance window opens.
+	NextWindow *metav1.Time `json:"nextWindow,omitempty"`
+}
+
+// PendingOperation tracks an update spanning several reconciles, such as one
+// that needs the instance stopped, so it can be resumed after a provider
+// restart.
+type PendingOperation struct {
+	// Operations are the updates applied while the instance is stopped, in
+	// execution order.
+	Operations []string `json:"operations"`
+
+	// Phase is one of Stopping, Applying or Starting.
+	Phase string `json:"phase"`
+
+	// Step is the index of the operation being applied.
+	// +optional
+	Step int `json:"step,omitempty"`
+
+	// CreatedVolumes are the volumes created by the operation that are not
+	// attached yet, keyed by device name.
+	// +optional
+	CreatedVolumes map[string]string `json:"createdVolumes,omitempty"`
+
+	StartedAt metav1.Time `json:"startedAt"`
 }
 
 // ComputeObservation are the observable fields of a Compute.
 type ComputeObservation struct {
-	ObservableField string `json:"observableField,omitempty"`
-	State           string `json:"state"`
-	InstanceID      string `json:"instanceID"`
+	State      string `json:"state"`
+	InstanceID string `json:"instanceID"`
+
+	InstanceType     string       `json:"instanceType,omitempty"`
+	Architecture     string       `json:"architecture,omitempty"`
+	AvailabilityZone string       `json:"availabilityZone,omitempty"`
+	PrivateIP        string       `json:"privateIP,omitempty"`
+	PublicIP         string       `json:"publicIP,omitempty"`
+	PrivateDNS       string       `json:"privateDNS,omitempty"`
+	PublicDNS        string       `json:"publicDNS,omitempty"`
+	LaunchTime       *metav1.Time `json:"launchTime,omitempty"`
+
+	SecurityGroups []string           `json:"securityGroups,omitempty"`
+	Volumes        []VolumeAttachment `json:"volumes,omitempty"`
+
+	// StorageVolumes records the volume backing each storage entry, keyed by
+	// the device name of the entry. Volumes are matched by these IDs rather
+	// than by the device they are attached to.
+	StorageVolumes map[string]StorageVolume `json:"storageVolumes,omitempty"`
+
+	// VolumeRemovals are the volumes being detached, or detached, whose
+	// removal policy has not been applied yet.
+	VolumeRemovals []VolumeRemoval `json:"volumeRemovals,omitempty"`
+
+	Replacement      *Replacement      `json:"replacement,omitempty"`
+	PendingOperation *PendingOperation `json:"pendingOperation,omitempty"`
+
+	// ReplacementAttempts counts the replacement instances that failed to
+	// start. It seeds the idempotency token of the next launch, so EC2 does
+	// not hand back the failed instance.
+	ReplacementAttempts int `json:"replacementAttempts,omitempty"`
+
+	// RejectedInstanceType is an instance type EC2 refused to apply to the
+	// stopped instance, which was rolled back to its type. It is not tried
+	// again until the desired instance type changes.
+	RejectedInstanceType string `json:"rejectedInstanceType,omitempty"`
+
+	// DeferredUpdates are the updates that stop the instance, held back until
+	// the next maintenance window.
+	DeferredUpdates *DeferredUpdates `json:"deferredUpdates,omitempty"`
+
+	// UpdatePlan lists the updates held back by a Manual or ReportOnly
+	// update policy.
+	UpdatePlan *UpdatePlan `json:"updatePlan,omitempty"`
 }
 
 // A ComputeSpec defines the desired state of a Compute.
 type ComputeSpec struct {
 	xpv1.ResourceSpec `json:",inline"`
 	ForProvider       ComputeParameters `json:"forProvider"`
+
+	// UpdatePolicy decides whether the updates found between the spec and
+	// the instance are applied right away, once approved, or never.
+	// +kubebuilder:validation:Enum=Automatic;Manual;ReportOnly
+	// +kubebuilder:default=Automatic
+	// +optional
+	UpdatePolicy UpdatePolicy `json:"updatePolicy,omitempty"`
+
+	// MaintenanceWindow restricts when updates that stop the instance run,
+	// falling back to the one of the ProviderConfig.
+	// +optional
+	MaintenanceWindow *apisv1alpha1.MaintenanceWindow `json:"maintenanceWindow,omitempty"`
 }
 
 // A ComputeStatus represents the observed state of a Compute.
@@ -81,6 +592,13 @@ type ComputeStatus struct {
 // +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
 // +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
 // +kubebuilder:printcolumn:name="EXTERNAL-NAME",type="string",JSONPath=".metadata.annotations.crossplane\\.io/external-name"
+// +kubebuilder:printcolumn:name="STATE",type="string",JSONPath=".status.atProvider.state"
+// +kubebuilder:printcolumn:name="TYPE",type="string",JSONPath=".status.atProvider.instanceType"
+// +kubebuilder:printcolumn:name="PRIVATE-IP",type="string",JSONPath=".status.atProvider.privateIP"
+// +kubebuilder:printcolumn:name="PUBLIC-IP",type="string",JSONPath=".status.atProvider.publicIP"
+// +kubebuilder:printcolumn:name="ZONE",type="string",JSONPath=".status.atProvider.availabilityZone",priority=1
+// +kubebuilder:printcolumn:name="ARCH",type="string",JSONPath=".status.atProvider.architecture",priority=1
+// +kubebuilder:printcolumn:name="PUBLIC-DNS",type="string",JSONPath=".status.atProvider.publicDNS",priority=1
 // +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,customcomputeprovider}
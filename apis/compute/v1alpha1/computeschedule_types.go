@@ -0,0 +1,138 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// Annotations set by a ComputeSchedule on the Computes it selects.
+const (
+	// AnnotationKeyPowerState overrides the power state of the Compute spec.
+	AnnotationKeyPowerState = "customcomputeprovider.crossplane.io/power-state"
+
+	// AnnotationKeySchedule names the ComputeSchedule that set the power
+	// state override.
+	AnnotationKeySchedule = "customcomputeprovider.crossplane.io/schedule"
+)
+
+// ScheduleWindow is a period during which the selected Computes run. Both ends
+// are standard five field cron expressions, e.g. "0 8 * * 1-5".
+type ScheduleWindow struct {
+	Start string `json:"start"`
+	Stop  string `json:"stop"`
+}
+
+// ComputeScheduleParameters are the configurable fields of a ComputeSchedule.
+type ComputeScheduleParameters struct {
+	// Windows during which the selected Computes run. They are parked in the
+	// stop state outside of every window.
+	// +kubebuilder:validation:MinItems=1
+	Windows []ScheduleWindow `json:"windows"`
+
+	// Timezone the cron expressions are evaluated in, as an IANA name.
+	// +kubebuilder:default=UTC
+	// +optional
+	Timezone string `json:"timezone,omitempty"`
+
+	// ComputeSelector selects the Computes by label.
+	ComputeSelector metav1.LabelSelector `json:"computeSelector"`
+
+	// StopState is the power state of the Computes outside of the windows.
+	// +kubebuilder:validation:Enum=Stopped;Hibernated
+	// +kubebuilder:default=Stopped
+	// +optional
+	StopState PowerState `json:"stopState,omitempty"`
+}
+
+// ComputeScheduleObservation are the observable fields of a ComputeSchedule.
+type ComputeScheduleObservation struct {
+	// PowerState the selected Computes are currently kept in.
+	PowerState PowerState `json:"powerState,omitempty"`
+
+	// NextTransition is when the power state changes next, to NextPowerState.
+	NextTransition *metav1.Time `json:"nextTransition,omitempty"`
+	NextPowerState PowerState   `json:"nextPowerState,omitempty"`
+
+	// LastAction is the last transition applied to the Computes, one of
+	// Started, Stopped or Hibernated, at LastActionTime.
+	LastAction     string       `json:"lastAction,omitempty"`
+	LastActionTime *metav1.Time `json:"lastActionTime,omitempty"`
+
+	// Computes are the names of the Computes the schedule applies to.
+	Computes []string `json:"computes,omitempty"`
+
+	// ClaimedComputes are the names of the selected Computes another schedule
+	// already applies to, they are left to it.
+	ClaimedComputes []string `json:"claimedComputes,omitempty"`
+}
+
+// A ComputeScheduleSpec defines the desired state of a ComputeSchedule.
+type ComputeScheduleSpec struct {
+	xpv1.ResourceSpec `json:",inline"`
+	ForProvider       ComputeScheduleParameters `json:"forProvider"`
+}
+
+// A ComputeScheduleStatus represents the observed state of a ComputeSchedule.
+type ComputeScheduleStatus struct {
+	xpv1.ResourceStatus `json:",inline"`
+	AtProvider          ComputeScheduleObservation `json:"atProvider,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A ComputeSchedule starts and stops the Computes it selects on a schedule.
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="POWER-STATE",type="string",JSONPath=".status.atProvider.powerState"
+// +kubebuilder:printcolumn:name="NEXT",type="date",JSONPath=".status.atProvider.nextTransition"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,customcomputeprovider}
+type ComputeSchedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ComputeScheduleSpec   `json:"spec"`
+	Status ComputeScheduleStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ComputeScheduleList contains a list of ComputeSchedule
+type ComputeScheduleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ComputeSchedule `json:"items"`
+}
+
+// ComputeSchedule type metadata.
+var (
+	ComputeScheduleKind             = reflect.TypeOf(ComputeSchedule{}).Name()
+	ComputeScheduleGroupKind        = schema.GroupKind{Group: Group, Kind: ComputeScheduleKind}.String()
+	ComputeScheduleKindAPIVersion   = ComputeScheduleKind + "." + SchemeGroupVersion.String()
+	ComputeScheduleGroupVersionKind = SchemeGroupVersion.WithKind(ComputeScheduleKind)
+)
+
+func init() {
+	SchemeBuilder.Register(&ComputeSchedule{}, &ComputeScheduleList{})
+}
@@ -29,8 +29,46 @@ import (
 type ProviderConfigSpec struct {
 	// Credentials required to authenticate to this provider.
 	Credentials ProviderCredentials `json:"credentials"`
+
+	// MaintenanceWindow is the default maintenance window of the resources
+	// using this ProviderConfig that do not set their own.
+	// +optional
+	MaintenanceWindow *MaintenanceWindow `json:"maintenanceWindow,omitempty"`
+}
+
+// A MaintenanceWindow restricts when updates that stop an instance run. They
+// are postponed until the next range comes, other updates apply right away.
+type MaintenanceWindow struct {
+	// Ranges of the window.
+	// +kubebuilder:validation:MinItems=1
+	Ranges []MaintenanceRange `json:"ranges"`
+
+	// Timezone the ranges are given in, as an IANA name.
+	// +kubebuilder:default=UTC
+	// +optional
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// A MaintenanceRange is a time range on some days of the week. A range ending
+// before it starts spans midnight and ends on the next day.
+type MaintenanceRange struct {
+	// Days the range starts on, every day when empty.
+	// +optional
+	Days []Weekday `json:"days,omitempty"`
+
+	// Start of the range, as HH:MM.
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+
+	// End of the range, as HH:MM.
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	End string `json:"end"`
 }
 
+// A Weekday is a day of the week.
+// +kubebuilder:validation:Enum=Monday;Tuesday;Wednesday;Thursday;Friday;Saturday;Sunday
+type Weekday string
+
 // ProviderCredentials required to authenticate.
 type ProviderCredentials struct {
 	// Source of the provider credentials.
@@ -2,19 +2,33 @@ package volume
 
 import (
 	"context"
+	"errors"
 
 	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
 )
 
+// ErrPending is returned by a command that is waiting on EC2, it has to be
+// run again on a later reconcile to complete.
+var ErrPending = errors.New("volume command pending")
+
 type VolumeCommand interface {
 	Run(ctx context.Context, c *provider.EC2Client) error
 	GetType() string
+
+	// RequiresStop reports whether the command can only run while the
+	// instance is stopped.
+	RequiresStop() bool
 }
 
 type BaseCommand struct {
-	commandType string
+	commandType  string
+	requiresStop bool
 }
 
 func (b *BaseCommand) GetType() string {
 	return b.commandType
 }
+
+func (b *BaseCommand) RequiresStop() bool {
+	return b.requiresStop
+}
@@ -3,11 +3,13 @@ package volume
 import (
 	"context"
 	"errors"
-	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
 	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+	k8stypes "k8s.io/apimachinery/pkg/types"
 )
 
 type CreateVolumeCommand struct {
@@ -17,26 +19,59 @@ type CreateVolumeCommand struct {
 	VolumeType string
 	DiskSize   int32
 	SubnetId   string
+
+	IOPS                *int32
+	Throughput          *int32
+	KMSKeyID            string
+	SnapshotID          string
+	Encrypted           bool
+	DeleteOnTermination bool
+
+	// VolumeId is set once the volume has been created, a command carrying
+	// it only waits for the volume to become available and attaches it.
+	VolumeId string
+
+	// Owner tags the volume with the UID of the Compute that owns it.
+	Owner k8stypes.UID
+
+	// ClientToken makes the creation idempotent, retrying it with the same
+	// token returns the volume created by the first attempt.
+	ClientToken string
 }
 
-func NewVolumeCommand(instanceID, subnetID, deviceName, volumeType string, diskSize int32) *CreateVolumeCommand {
+// NewVolumeCommand creates the volume of the storage entry and attaches it,
+// a pinned volume is only attached.
+func NewVolumeCommand(instanceID, subnetID string, storage v1alpha1.Storage) *CreateVolumeCommand {
 	return &CreateVolumeCommand{
-		BaseCommand: BaseCommand{commandType: "CreateVolume"},
-		InstanceId:  instanceID,
-		DeviceName:  deviceName,
-		VolumeType:  volumeType,
-		DiskSize:    diskSize,
-		SubnetId:    subnetID,
+		BaseCommand:         BaseCommand{commandType: "CreateVolume"},
+		InstanceId:          instanceID,
+		DeviceName:          storage.DeviceName,
+		VolumeType:          storage.InstanceDisk,
+		DiskSize:            storage.DiskSize,
+		SubnetId:            subnetID,
+		IOPS:                storage.IOPS,
+		Throughput:          storage.Throughput,
+		KMSKeyID:            storage.KMSKeyID,
+		SnapshotID:          storage.SnapshotID,
+		Encrypted:           provider.Encrypted(storage),
+		DeleteOnTermination: provider.DeleteOnTermination(storage) && storage.VolumeID == "",
+		VolumeId:            storage.VolumeID,
 	}
 }
 
 func (c *CreateVolumeCommand) Run(ctx context.Context, client *provider.EC2Client) error {
-	availabilityZone, err := getAvailabilityZone(ctx, client.Client, c.SubnetId)
-	if err != nil {
-		return err
+	if c.VolumeId == "" {
+		availabilityZone, err := getAvailabilityZone(ctx, client.Client, c.SubnetId)
+		if err != nil {
+			return err
+		}
+
+		if err := c.createVolume(ctx, client, availabilityZone); err != nil {
+			return err
+		}
 	}
 
-	return c.createVolume(ctx, client, c.InstanceId, c.DeviceName, c.VolumeType, availabilityZone, c.DiskSize)
+	return c.attachVolume(ctx, client, c.InstanceId, c.DeviceName)
 }
 
 func getAvailabilityZone(ctx context.Context, c *ec2.Client, subnetID string) (string, error) {
@@ -49,51 +84,79 @@ func getAvailabilityZone(ctx context.Context, c *ec2.Client, subnetID string) (s
 	}
 
 	if len(output.Subnets) > 0 {
-		return *output.Subnets[0].AvailabilityZoneId, nil
+		return *output.Subnets[0].AvailabilityZone, nil
 	}
 
 	return "", errors.New("subnet not found")
 }
 
-func (e *CreateVolumeCommand) createVolume(ctx context.Context, client *provider.EC2Client, instanceId, deviceName, volumeType, availabilityZone string, volumeSize int32) error {
-	volume, err := client.Client.CreateVolume(ctx, &ec2.CreateVolumeInput{
-		VolumeType:       types.VolumeType(volumeType),
-		Size:             &volumeSize,
+func (c *CreateVolumeCommand) createVolume(ctx context.Context, client *provider.EC2Client, availabilityZone string) error {
+	input := &ec2.CreateVolumeInput{
+		VolumeType:       types.VolumeType(c.VolumeType),
+		Size:             &c.DiskSize,
 		AvailabilityZone: &availabilityZone,
-	})
+		Iops:             c.IOPS,
+		Throughput:       c.Throughput,
+		Encrypted:        &c.Encrypted,
+	}
+	if c.KMSKeyID != "" {
+		input.KmsKeyId = &c.KMSKeyID
+	}
+	if c.SnapshotID != "" {
+		input.SnapshotId = &c.SnapshotID
+	}
+	if c.ClientToken != "" {
+		input.ClientToken = &c.ClientToken
+	}
+	if c.Owner != "" {
+		input.TagSpecifications = []types.TagSpecification{
+			{
+				ResourceType: types.ResourceTypeVolume,
+				Tags: []types.Tag{
+					{Key: aws.String(provider.OwnerTagKey), Value: aws.String(string(c.Owner))},
+				},
+			},
+		}
+	}
+
+	volume, err := client.Client.CreateVolume(ctx, input)
 
 	if err != nil {
 		return err
 	}
 
-	ticker := time.NewTicker(time.Second * 10)
-
-	for {
-		<-ticker.C
-
-		output, err := client.Client.DescribeVolumeStatus(ctx, &ec2.DescribeVolumeStatusInput{
-			VolumeIds: []string{*volume.VolumeId},
-		})
+	c.VolumeId = *volume.VolumeId
+	return nil
+}
 
-		if err != nil {
-			return err
-		}
+// attachVolume attaches the volume once it is available. It returns
+// ErrPending while the volume is still being created. A volume attached on
+// its own is kept when the instance terminates, unless told otherwise.
+func (c *CreateVolumeCommand) attachVolume(ctx context.Context, client *provider.EC2Client, instanceId, deviceName string) error {
+	output, err := client.Client.DescribeVolumes(ctx, &ec2.DescribeVolumesInput{
+		VolumeIds: []string{c.VolumeId},
+	})
 
-		status := output.VolumeStatuses[0].VolumeStatus.Status
-		desiredState := types.VolumeStatusInfoStatus(types.StateAvailable)
+	if err != nil {
+		return err
+	}
 
-		if status != desiredState {
-			continue
-		}
+	if len(output.Volumes) == 0 {
+		return errors.New("volume not found")
+	}
 
-		break
+	if output.Volumes[0].State != types.VolumeStateAvailable {
+		return ErrPending
 	}
 
 	_, err = client.Client.AttachVolume(ctx, &ec2.AttachVolumeInput{
 		Device:     &deviceName,
 		InstanceId: &instanceId,
-		VolumeId:   volume.VolumeId,
+		VolumeId:   &c.VolumeId,
 	})
+	if err != nil || !c.DeleteOnTermination {
+		return err
+	}
 
-	return err
+	return setDeleteOnTermination(ctx, client, instanceId, deviceName, c.DeleteOnTermination)
 }
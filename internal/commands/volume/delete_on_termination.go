@@ -0,0 +1,45 @@
+package volume
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+)
+
+// DeleteOnTerminationCommand sets whether an attached volume is deleted along
+// with the instance.
+type DeleteOnTerminationCommand struct {
+	BaseCommand
+	InstanceId          string
+	DeviceName          string
+	DeleteOnTermination bool
+}
+
+func NewDeleteOnTerminationCommand(instanceID, deviceName string, deleteOnTermination bool) *DeleteOnTerminationCommand {
+	return &DeleteOnTerminationCommand{
+		BaseCommand:         BaseCommand{commandType: "DeleteOnTermination"},
+		InstanceId:          instanceID,
+		DeviceName:          deviceName,
+		DeleteOnTermination: deleteOnTermination,
+	}
+}
+
+func (d *DeleteOnTerminationCommand) Run(ctx context.Context, client *provider.EC2Client) error {
+	return setDeleteOnTermination(ctx, client, d.InstanceId, d.DeviceName, d.DeleteOnTermination)
+}
+
+func setDeleteOnTermination(ctx context.Context, client *provider.EC2Client, instanceID, deviceName string, deleteOnTermination bool) error {
+	_, err := client.Client.ModifyInstanceAttribute(ctx, &ec2.ModifyInstanceAttributeInput{
+		InstanceId: &instanceID,
+		BlockDeviceMappings: []types.InstanceBlockDeviceMappingSpecification{
+			{
+				DeviceName: &deviceName,
+				Ebs:        &types.EbsInstanceBlockDeviceSpecification{DeleteOnTermination: &deleteOnTermination},
+			},
+		},
+	})
+
+	return err
+}
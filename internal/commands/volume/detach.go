@@ -4,6 +4,8 @@ import (
 	"context"
 
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
 	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
 )
 
@@ -12,18 +14,46 @@ type DetachVolumeCommand struct {
 	VolumeId   string
 	DeviceName string
 	InstanceId string
+
+	// RemovalPolicy is applied to the volume once it is detached.
+	RemovalPolicy v1alpha1.RemovalPolicy
+
+	// Snapshot takes a snapshot of the volume before its removal policy is
+	// applied.
+	Snapshot bool
+
+	AttachmentState types.VolumeAttachmentState
 }
 
-func NewDetachVolumeCommand(volumeID, deviceName, instanceID string) *DetachVolumeCommand {
+func NewDetachVolumeCommand(volumeID, deviceName, instanceID string, policy v1alpha1.RemovalPolicy, state types.VolumeAttachmentState) *DetachVolumeCommand {
+	if policy == "" {
+		policy = v1alpha1.RemovalPolicyRetain
+	}
+
+	// Detaching a volume the guest still has mounted may corrupt it, the
+	// instance is stopped first.
 	return &DetachVolumeCommand{
-		VolumeId:   volumeID,
-		DeviceName: deviceName,
-		InstanceId: instanceID,
+		BaseCommand:     BaseCommand{commandType: "DetachVolume", requiresStop: true},
+		VolumeId:        volumeID,
+		DeviceName:      deviceName,
+		InstanceId:      instanceID,
+		RemovalPolicy:   policy,
+		AttachmentState: state,
 	}
 }
 
+// Run requests the detachment and returns ErrPending until the volume is no
+// longer attached to the instance.
 func (d *DetachVolumeCommand) Run(ctx context.Context, client *provider.EC2Client) error {
-	return d.detachVolume(ctx, client, d.DeviceName, d.InstanceId, d.VolumeId)
+	if d.AttachmentState == types.VolumeAttachmentStateDetaching {
+		return ErrPending
+	}
+
+	if err := d.detachVolume(ctx, client, d.DeviceName, d.InstanceId, d.VolumeId); err != nil {
+		return err
+	}
+
+	return ErrPending
 }
 
 func (d *DetachVolumeCommand) detachVolume(ctx context.Context, c *provider.EC2Client, deviceName, instanceId, volumeId string) error {
@@ -0,0 +1,46 @@
+package volume
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+)
+
+// ModifyVolumeCommand changes the size, type and performance of an attached
+// volume. EBS applies the change while the volume is in use and only accepts
+// a new one once the previous modification completed, so every change to a
+// volume is sent as a single request.
+type ModifyVolumeCommand struct {
+	BaseCommand
+	VolumeID   string
+	DiskSize   *int32
+	VolumeType string
+	IOPS       *int32
+	Throughput *int32
+}
+
+func NewModifyVolumeCommand(volumeID string) *ModifyVolumeCommand {
+	return &ModifyVolumeCommand{
+		BaseCommand: BaseCommand{commandType: "ModifyVolume"},
+		VolumeID:    volumeID,
+	}
+}
+
+// HasChanges reports whether the command carries any change to apply.
+func (m *ModifyVolumeCommand) HasChanges() bool {
+	return m.DiskSize != nil || m.VolumeType != "" || m.IOPS != nil || m.Throughput != nil
+}
+
+func (m *ModifyVolumeCommand) Run(ctx context.Context, c *provider.EC2Client) error {
+	_, err := c.Client.ModifyVolume(ctx, &ec2.ModifyVolumeInput{
+		VolumeId:   &m.VolumeID,
+		Size:       m.DiskSize,
+		VolumeType: types.VolumeType(m.VolumeType),
+		Iops:       m.IOPS,
+		Throughput: m.Throughput,
+	})
+
+	return err
+}
@@ -19,22 +19,29 @@ package compute
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"strings"
 	"time"
 
 	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"github.com/crossplane/crossplane-runtime/pkg/connection"
 	"github.com/crossplane/crossplane-runtime/pkg/controller"
 	"github.com/crossplane/crossplane-runtime/pkg/event"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/crossplane/crossplane-runtime/pkg/ratelimiter"
 	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
@@ -43,6 +50,7 @@ import (
 	"github.com/crossplane/provider-customcomputeprovider/internal/features"
 	validation "github.com/crossplane/provider-customcomputeprovider/internal/observer"
 	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+	"github.com/crossplane/provider-customcomputeprovider/internal/shared"
 	"github.com/crossplane/provider-customcomputeprovider/internal/updater"
 )
 
@@ -54,6 +62,74 @@ const (
 
 	errNewClient = "cannot create new Service"
 	errAwsClient = "cannot create aws client"
+
+	errGetUserDataSecret    = "cannot get user data secret"
+	errGetUserDataConfigMap = "cannot get user data config map"
+	errUserDataKeyNotFound  = "user data key %q not found in %s/%s"
+
+	errGetConnectionSecret = "cannot get connection secret"
+	errStorePrivateKey     = "cannot store the private key in the connection secret"
+)
+
+// operationPollInterval is how often a Compute is reconciled while an update
+// spanning several reconciles is in progress.
+const operationPollInterval = 15 * time.Second
+
+// Reasons of the events recorded for a Compute.
+const (
+	reasonTerminatedExternally event.Reason = "TerminatedExternally"
+	reasonSpotInterruption     event.Reason = "SpotInterruption"
+	reasonUpdatePlanned        event.Reason = "UpdatePlanned"
+)
+
+// Keys of the connection details published for a Compute.
+const (
+	keyInstanceID = "instanceID"
+	keyPrivateIP  = "privateIP"
+	keyPublicIP   = "publicIP"
+	keyPrivateDNS = "privateDNS"
+	keyPublicDNS  = "publicDNS"
+	keyKeyName    = "keyName"
+	keyPrivateKey = "privateKey"
+
+	defaultSSHUsername = "ec2-user"
+)
+
+// typeStorageValid tells whether the storage spec can be applied to the
+// volumes of the instance.
+const (
+	typeStorageValid             xpv1.ConditionType   = "StorageValid"
+	reasonStorageValid           xpv1.ConditionReason = "Valid"
+	reasonVolumeShrinkRefused    xpv1.ConditionReason = "VolumeShrinkRefused"
+	reasonVolumeReplacedOnShrink xpv1.ConditionReason = "VolumeReplacedOnShrink"
+)
+
+// typeUpdatePending tells whether updates are held back by the update policy.
+const (
+	typeUpdatePending      xpv1.ConditionType   = "UpdatePending"
+	reasonNoUpdates        xpv1.ConditionReason = "NoUpdates"
+	reasonAwaitingApproval xpv1.ConditionReason = "AwaitingApproval"
+	reasonReportOnly       xpv1.ConditionReason = "ReportOnly"
+	reasonPlanApproved     xpv1.ConditionReason = "PlanApproved"
+)
+
+// typeUpdatesDeferred tells whether updates that stop the instance wait on the
+// next maintenance window.
+const (
+	typeUpdatesDeferred            xpv1.ConditionType   = "UpdatesDeferred"
+	reasonOutsideMaintenanceWindow xpv1.ConditionReason = "OutsideMaintenanceWindow"
+	reasonInvalidMaintenanceWindow xpv1.ConditionReason = "InvalidMaintenanceWindow"
+	reasonNoDeferredUpdates        xpv1.ConditionReason = "NoDeferredUpdates"
+)
+
+// Reasons of the Ready condition of an instance that is not available.
+const (
+	reasonInstanceTerminated xpv1.ConditionReason = "InstanceTerminated"
+	reasonInstanceStopping   xpv1.ConditionReason = "InstanceStopping"
+	reasonInstanceStopped    xpv1.ConditionReason = "InstanceStopped"
+	reasonInstanceImpaired   xpv1.ConditionReason = "InstanceImpaired"
+	reasonStatusChecks       xpv1.ConditionReason = "StatusChecksInitializing"
+	reasonSpotInterrupted    xpv1.ConditionReason = "SpotInterrupted"
 )
 
 // A NoOpService does nothing.
@@ -72,6 +148,8 @@ func Setup(mgr ctrl.Manager, o controller.Options) error {
 		cps = append(cps, connection.NewDetailsManager(mgr.GetClient(), apisv1alpha1.StoreConfigGroupVersionKind))
 	}
 
+	recorder := event.NewAPIRecorder(mgr.GetEventRecorderFor(name))
+
 	r := managed.NewReconciler(mgr,
 		resource.ManagedKind(v1alpha1.ComputeGroupVersionKind),
 		managed.WithExternalConnecter(&connector{
@@ -79,11 +157,16 @@ func Setup(mgr ctrl.Manager, o controller.Options) error {
 			usage:        resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1.ProviderConfigUsage{}),
 			newServiceFn: newNoOpService,
 			logger:       o.Logger,
+			recorder:     recorder,
 		},
 		),
+		// The external-name holds the instance ID, which is only known once
+		// the instance has been created or when an existing one is imported.
+		managed.WithInitializers(),
 		managed.WithLogger(o.Logger.WithValues("controller", name)),
 		managed.WithPollInterval(o.PollInterval),
-		managed.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))),
+		managed.WithPollIntervalHook(pollInterval),
+		managed.WithRecorder(recorder),
 		managed.WithConnectionPublishers(cps...))
 
 	return ctrl.NewControllerManagedBy(mgr).
@@ -94,6 +177,22 @@ func Setup(mgr ctrl.Manager, o controller.Options) error {
 		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
 }
 
+// pollInterval shortens the poll interval of a Compute while an instance
+// replacement or a pending operation is in progress, so each of their steps
+// is taken as soon as EC2 allows it.
+func pollInterval(mg resource.Managed, interval time.Duration) time.Duration {
+	cr, ok := mg.(*v1alpha1.Compute)
+	if !ok || interval < operationPollInterval {
+		return interval
+	}
+
+	if cr.Status.AtProvider.Replacement != nil || cr.Status.AtProvider.PendingOperation != nil || len(cr.Status.AtProvider.VolumeRemovals) > 0 {
+		return operationPollInterval
+	}
+
+	return interval
+}
+
 // A connector is expected to produce an ExternalClient when its Connect method
 // is called.
 type connector struct {
@@ -101,6 +200,7 @@ type connector struct {
 	usage        resource.Tracker
 	newServiceFn func(creds []byte) (interface{}, error)
 	logger       logging.Logger
+	recorder     event.Recorder
 }
 
 // Connect typically produces an ExternalClient by:
@@ -162,7 +262,21 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 		return nil, errors.Wrap(err, errNewClient)
 	}
 
-	return &external{service: svc, logger: c.logger, kube: c.kube}, nil
+	// An invalid maintenance window holds back the updates stopping the
+	// instance, it is reported on the Compute rather than failing it.
+	var windowErr error
+	if err := updater.ValidateMaintenanceWindow(pc.Spec.MaintenanceWindow); err != nil {
+		windowErr = errors.Wrapf(err, "ProviderConfig %s", pc.GetName())
+	}
+
+	return &external{
+		service:           svc,
+		logger:            c.logger,
+		kube:              c.kube,
+		recorder:          c.recorder,
+		maintenanceWindow: pc.Spec.MaintenanceWindow,
+		maintenanceErr:    windowErr,
+	}, nil
 }
 
 // An ExternalClient observes, then either creates, updates, or deletes an
@@ -170,9 +284,15 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 type external struct {
 	// A 'client' used to connect to the external resource API. In practice this
 	// would be something like an AWS SDK client.
-	service interface{}
-	logger  logging.Logger
-	kube    client.Client
+	service  interface{}
+	logger   logging.Logger
+	kube     client.Client
+	recorder event.Recorder
+
+	// maintenanceWindow is the default maintenance window of the
+	// ProviderConfig, maintenanceErr tells why it is invalid.
+	maintenanceWindow *apisv1alpha1.MaintenanceWindow
+	maintenanceErr    error
 }
 
 func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
@@ -194,35 +314,116 @@ func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 	}
 
 	resourceConfig := cr.Spec.ForProvider.InstanceConfig
-	if cr.Status.AtProvider.InstanceID == "" {
-		log.Info("instance ID not found, resource does not exist")
-		return managed.ExternalObservation{ResourceExists: false}, nil
+	if err := c.resolveUserData(ctx, &resourceConfig); err != nil {
+		return managed.ExternalObservation{}, err
 	}
+	resourceConfig.PowerState = provider.ScheduledPowerState(cr, &resourceConfig)
 
-	log = log.WithValues("instanceID", cr.Status.AtProvider.InstanceID)
-
-	resourceFound, currentResource, err := client.Observe(ctx, cr, resourceConfig.InstanceName)
+	resourceFound, currentResource, err := client.Observe(ctx, cr)
 	if err != nil {
 		log.Info("failed to observe resource", "error", err)
 		return managed.ExternalObservation{}, err
 	}
 
+	if !resourceFound && currentResource != nil {
+		return c.observeTerminated(ctx, client, cr, currentResource, log)
+	}
+
 	if !resourceFound {
 		log.Info("resource not found", "the program will initialize the creation",
 			"region", cr.Spec.ForProvider.AWSConfig.Region,
-			"config", resourceConfig,
+			"config", cr.Spec.ForProvider.InstanceConfig,
 		)
 		return managed.ExternalObservation{ResourceExists: false}, nil
 	}
 
+	instanceID := *currentResource.InstanceId
+	if cr.Status.AtProvider.InstanceID != instanceID {
+		log.Info("adopting existing instance", "instanceID", instanceID)
+	}
+	setObservation(&cr.Status.AtProvider, currentResource)
+
+	volumeState, err := trackVolumes(ctx, client, cr, currentResource)
+	if err != nil {
+		log.Info("failed to track volumes", "error", err)
+		return managed.ExternalObservation{}, err
+	}
+	c.reportShrinks(cr, shared.NewCommandAnalyzer().AnalyzeShrinks(volumeState))
+
+	var instanceStatus *ec2types.InstanceStatus
+	if currentResource.State.Name == ec2types.InstanceStateNameRunning {
+		instanceStatus, err = client.GetInstanceStatus(ctx, instanceID)
+		if err != nil {
+			log.Info("failed to get instance status checks", "error", err)
+			return managed.ExternalObservation{}, err
+		}
+	}
+	cr.SetConditions(instanceCondition(currentResource, instanceStatus))
+
+	if err := c.syncExternalName(ctx, cr); err != nil {
+		return managed.ExternalObservation{}, err
+	}
+
 	log = log.WithValues(
+		"instanceID", instanceID,
 		"state", currentResource.State.Name,
 		"type", currentResource.InstanceType,
 		"launchTime", currentResource.LaunchTime,
 	)
 
+	if meta.WasDeleted(cr) {
+		cr.SetConditions(xpv1.Deleting())
+		return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, nil
+	}
+
+	if cr.Status.AtProvider.Replacement != nil {
+		log.Info("instance replacement in progress",
+			"previous", cr.Status.AtProvider.Replacement.PreviousInstanceID,
+			"phase", cr.Status.AtProvider.Replacement.Phase,
+		)
+		return managed.ExternalObservation{
+			ResourceExists:   true,
+			ResourceUpToDate: false,
+		}, nil
+	}
+
+	if p := cr.Status.AtProvider.PendingOperation; p != nil {
+		log.Info("pending operation in progress",
+			"operations", p.Operations,
+			"phase", p.Phase,
+			"step", p.Step,
+		)
+		return managed.ExternalObservation{
+			ResourceExists:    true,
+			ResourceUpToDate:  false,
+			ConnectionDetails: connectionDetails(cr, currentResource),
+		}, nil
+	}
+
+	if removals := cr.Status.AtProvider.VolumeRemovals; len(removals) > 0 {
+		log.Info("volume removals in progress", "removals", removals)
+		return managed.ExternalObservation{
+			ResourceExists:    true,
+			ResourceUpToDate:  false,
+			ConnectionDetails: connectionDetails(cr, currentResource),
+		}, nil
+	}
+
+	if isTransitioning(currentResource) {
+		log.Info("instance is transitioning, updates are postponed until it settles")
+		return managed.ExternalObservation{
+			ResourceExists:    true,
+			ResourceUpToDate:  true,
+			ConnectionDetails: connectionDetails(cr, currentResource),
+		}, nil
+	}
+
 	validators := validation.NewCompositeValidator(c.logger, client)
-	validationResults := validators.ValidateAll(ctx, currentResource, &resourceConfig)
+	validationResults := validators.ValidateAll(ctx, currentResource, &resourceConfig, &cr.Status.AtProvider)
+	if validationResults.Err != nil {
+		log.Info("failed to compare the instance with the desired configuration", "error", validationResults.Err)
+		return managed.ExternalObservation{}, validationResults.Err
+	}
 
 	if validationResults.HasUpdates {
 		log.Info("resource needs update",
@@ -241,11 +442,41 @@ func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 				"tags":           resourceConfig.InstanceTags,
 			},
 		)
+
+		var plan *v1alpha1.UpdatePlan
+		if holdsUpdates(cr) {
+			plan = updater.NewUpdateOrchestrator(c.logger).Plan(updater.UpdateContext{
+				Context: ctx,
+				Current: currentResource,
+				Desired: &resourceConfig,
+				Status:  &cr.Status.AtProvider,
+				Owner:   cr.GetUID(),
+				Client:  client,
+				Logger:  c.logger,
+			}, validationResults.UpdatesRequired)
+		}
+
+		if !c.planUpdates(cr, plan) {
+			log.Info("updates held back by the update policy",
+				"policy", cr.Spec.UpdatePolicy,
+				"plan", cr.Status.AtProvider.UpdatePlan.Hash)
+			return managed.ExternalObservation{
+				ResourceExists:    true,
+				ResourceUpToDate:  true,
+				ConnectionDetails: connectionDetails(cr, currentResource),
+			}, nil
+		}
+
 		return managed.ExternalObservation{
-			ResourceExists:   true,
-			ResourceUpToDate: false,
+			ResourceExists:    true,
+			ResourceUpToDate:  false,
+			ConnectionDetails: connectionDetails(cr, currentResource),
 		}, nil
 	}
+	c.planUpdates(cr, nil)
+
+	cr.Status.AtProvider.DeferredUpdates = nil
+	reportDeferral(cr, nil)
 
 	log.Info("resource is up to date",
 		"currentState", map[string]interface{}{
@@ -270,7 +501,7 @@ func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 
 		// Return any details that may be required to connect to the external
 		// resource. These will be stored as the connection secret.
-		ConnectionDetails: managed.ConnectionDetails{},
+		ConnectionDetails: connectionDetails(cr, currentResource),
 	}, nil
 }
 
@@ -293,6 +524,27 @@ func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 	}
 
 	resourceConfig := cr.Spec.ForProvider.InstanceConfig
+	if err := c.resolveUserData(ctx, &resourceConfig); err != nil {
+		return managed.ExternalCreation{}, err
+	}
+
+	// EC2 launches every instance running, one created outside of its
+	// schedule is stopped by the first update once it leaves the pending
+	// state.
+	resourceConfig.PowerState = provider.ScheduledPowerState(cr, &resourceConfig)
+
+	var privateKey []byte
+	if generation := resourceConfig.KeyPairGeneration; generation != nil {
+		resourceConfig.KeyName = provider.GeneratedKeyName(cr)
+		privateKey, err = c.generateKeyPair(ctx, cc, cr, generation.Type)
+		if err != nil {
+			return managed.ExternalCreation{}, err
+		}
+	} else if resourceConfig.KeyName != "" {
+		if err := cc.ValidateKeyPair(ctx, resourceConfig.KeyName); err != nil {
+			return managed.ExternalCreation{}, err
+		}
+	}
 
 	log.Info("initiating instance creation",
 		"config", map[string]interface{}{
@@ -303,14 +555,21 @@ func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 			"securityGroups": resourceConfig.Networking.InstanceSecurityGroups,
 			"storage":        len(resourceConfig.Storage),
 			"tags":           resourceConfig.InstanceTags,
+			"keyName":        resourceConfig.KeyName,
+			"powerState":     provider.DesiredPowerState(&resourceConfig),
 		},
 	)
 
-	runOutput, err := cc.CreateInstance(ctx, resourceConfig)
+	// The token is seeded with the instance previously recorded for the
+	// resource, if any, so a relaunch is not mistaken for a retry.
+	runOutput, err := cc.CreateInstance(ctx, resourceConfig,
+		provider.WithClientToken(provider.ClientToken(cr.GetUID(), provider.InstanceID(cr))),
+		provider.WithOwner(cr.GetUID()),
+	)
 	if err != nil {
 		log.Debug("failed to create instance",
 			"error", err,
-			"config", resourceConfig,
+			"config", cr.Spec.ForProvider.InstanceConfig,
 		)
 		return managed.ExternalCreation{}, err
 	}
@@ -318,16 +577,20 @@ func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 	instanceID := *runOutput.Instances[0].InstanceId
 	instanceStatus := string(runOutput.Instances[0].State.Name)
 
+	// The external name is persisted by the managed reconciler once Create
+	// returns, even when it fails, so it is recorded before anything else.
+	meta.SetExternalName(cr, instanceID)
+
 	patchCR := cr.DeepCopy()
-	patchCR.Status.AtProvider = v1alpha1.ComputeObservation{
-		InstanceID: instanceID,
-		State:      instanceStatus,
-	}
+	patchCR.Status.AtProvider = v1alpha1.ComputeObservation{}
+	setObservation(&patchCR.Status.AtProvider, &runOutput.Instances[0])
 
 	mergeSource := client.MergeFrom(cr)
 
+	// The instance is launched at this point, a failed status update is not
+	// worth failing the creation for. The next observation fills the status.
 	if err := c.kube.Status().Patch(ctx, patchCR, mergeSource); err != nil {
-		return managed.ExternalCreation{}, errors.Wrap(err, "failed to update compute status after resource creation")
+		log.Info("failed to update compute status after resource creation", "error", err)
 	}
 
 	log.Info("instance created successfully",
@@ -348,10 +611,15 @@ func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 		},
 	)
 
+	details := connectionDetails(cr, &runOutput.Instances[0])
+	if privateKey != nil {
+		details[keyPrivateKey] = privateKey
+	}
+
 	return managed.ExternalCreation{
 		// Optionally return any details that may be required to connect to the
 		// external resource. These will be stored as the connection secret.
-		ConnectionDetails: managed.ConnectionDetails{},
+		ConnectionDetails: details,
 	}, nil
 }
 
@@ -362,12 +630,20 @@ func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.Ext
 	}
 
 	desiredConfig := cr.Spec.ForProvider.InstanceConfig
+	if err := c.resolveUserData(ctx, &desiredConfig); err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+
+	// A replacement instance is launched with the key pair of the original.
+	desiredConfig.KeyName = provider.KeyName(cr)
+	desiredConfig.PowerState = provider.ScheduledPowerState(cr, &desiredConfig)
+
 	client, err := clientSelector(ctx, c, cr.Spec.ForProvider.AWSConfig.Region)
 	if err != nil {
 		return managed.ExternalUpdate{}, err
 	}
 
-	instanceID := cr.Status.AtProvider.InstanceID
+	instanceID := provider.InstanceID(cr)
 
 	currentConfig, err := client.GetInstanceByID(ctx, instanceID)
 	if err != nil {
@@ -375,21 +651,42 @@ func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.Ext
 	}
 
 	validator := validation.NewCompositeValidator(c.logger, client)
-	validationResult := validator.ValidateAll(ctx, currentConfig, &desiredConfig)
+	validationResult := validator.ValidateAll(ctx, currentConfig, &desiredConfig, &cr.Status.AtProvider)
+	if validationResult.Err != nil {
+		return managed.ExternalUpdate{}, validationResult.Err
+	}
+
+	window, windowErr := c.maintenanceWindowOf(cr)
 
 	updateCtx := updater.UpdateContext{
 		Context: ctx,
 		Current: currentConfig,
 		Desired: &desiredConfig,
+		Status:  &cr.Status.AtProvider,
+		Owner:   cr.GetUID(),
 		Client:  client,
 		Logger:  c.logger,
+
+		Resource: cr,
+		Recorder: c.recorder,
+
+		MaintenanceWindow: window,
 	}
 
 	orchestrator := updater.NewUpdateOrchestrator(c.logger)
-	if err := orchestrator.ExecuteUpdates(updateCtx, validationResult.UpdatesRequired); err != nil {
+	updateErr := orchestrator.ExecuteUpdates(updateCtx, approvedUpdates(cr, validationResult.UpdatesRequired))
+	reportDeferral(cr, windowErr)
+
+	// A replacement moves the resource to a new instance, the external name
+	// has to follow it even when a later step of the update failed.
+	if err := c.syncExternalName(ctx, cr); err != nil {
 		return managed.ExternalUpdate{}, err
 	}
 
+	if updateErr != nil {
+		return managed.ExternalUpdate{}, updateErr
+	}
+
 	return managed.ExternalUpdate{
 		// Optionally return any details that may be required to connect to the
 		// external resource. These will be stored as the connection secret.
@@ -406,7 +703,7 @@ func (c *external) Delete(ctx context.Context, mg resource.Managed) error {
 	log := c.logger.WithValues(
 		"action", "delete",
 		"resource", cr.Name,
-		"instanceID", cr.Status.AtProvider.InstanceID,
+		"instanceID", provider.InstanceID(cr),
 		"instanceName", cr.Spec.ForProvider.InstanceConfig.InstanceName,
 		"region", cr.Spec.ForProvider.AWSConfig.Region,
 	)
@@ -417,11 +714,37 @@ func (c *external) Delete(ctx context.Context, mg resource.Managed) error {
 		return err
 	}
 
-	instanceID := cr.Status.AtProvider.InstanceID
+	instanceIDs := []string{provider.InstanceID(cr)}
+
+	// An instance replacement interrupted by the deletion may have left the
+	// previous instance running.
+	if r := cr.Status.AtProvider.Replacement; r != nil && r.PreviousInstanceID != instanceIDs[0] {
+		instanceIDs = append(instanceIDs, r.PreviousInstanceID)
+	}
+
+	for _, instanceID := range instanceIDs {
+		if instanceID == "" {
+			continue
+		}
+		if err := client.DeleteInstanceByID(ctx, instanceID); err != nil {
+			log.Info("failed to terminate instance", "instanceID", instanceID, "error", err)
+			return err
+		}
+	}
+
+	if resourceConfig.KeyPairGeneration != nil {
+		if err := client.DeleteKeyPair(ctx, provider.GeneratedKeyName(cr)); err != nil {
+			log.Info("failed to delete generated key pair", "error", err)
+			return err
+		}
+	}
+
+	cr.SetConditions(xpv1.Deleting())
 
 	log.Info("successfully initiated instance deletion",
 		"details", map[string]interface{}{
 			"timestamp": time.Now().UTC(),
+			"instances": instanceIDs,
 			"config": map[string]interface{}{
 				"subnet":         resourceConfig.Networking.SubnetID,
 				"securityGroups": resourceConfig.Networking.InstanceSecurityGroups,
@@ -429,7 +752,618 @@ func (c *external) Delete(ctx context.Context, mg resource.Managed) error {
 		},
 	)
 
-	return client.DeleteInstanceByID(ctx, instanceID)
+	return nil
+}
+
+// observeTerminated handles an instance that is shutting down or terminated.
+// Unless the provider did it itself, as part of a replacement or a deletion,
+// the instance was terminated outside of Crossplane and the recreate policy
+// decides whether a new one is launched.
+func (c *external) observeTerminated(ctx context.Context, client *provider.EC2Client, cr *v1alpha1.Compute, instance *ec2types.Instance, log logging.Logger) (managed.ExternalObservation, error) {
+	volumes := cr.Status.AtProvider.Volumes
+	previousState := cr.Status.AtProvider.State
+
+	// The last details observed are kept for an instance EC2 no longer
+	// describes.
+	if provider.IsVanished(instance) {
+		cr.Status.AtProvider.State = string(instance.State.Name)
+	} else {
+		setObservation(&cr.Status.AtProvider, instance)
+	}
+
+	// The finalizer is only released once EC2 confirms the termination.
+	if meta.WasDeleted(cr) {
+		if instance.State.Name == ec2types.InstanceStateNameShuttingDown {
+			log.Info("waiting for instance termination")
+			cr.SetConditions(xpv1.Deleting())
+			return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, nil
+		}
+
+		log.Info("instance terminated")
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+
+	if cr.Status.AtProvider.Replacement != nil {
+		log.Info("instance terminated as part of its replacement",
+			"phase", cr.Status.AtProvider.Replacement.Phase)
+		return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false}, nil
+	}
+
+	if provider.IsSpotInterruption(instance) {
+		return c.observeSpotInterruption(ctx, client, cr, instance, volumes, log)
+	}
+
+	instanceID := aws.ToString(instance.InstanceId)
+	if cr.Spec.ForProvider.RecreatePolicy == v1alpha1.RecreatePolicyReportOnly {
+		log.Info("instance terminated outside of crossplane, reporting only", "instanceID", instanceID)
+		// The termination is reported once, when it is first observed.
+		if !terminationObserved(previousState) {
+			c.recorder.Event(cr, event.Warning(reasonTerminatedExternally,
+				errors.Errorf("instance %s was terminated outside of Crossplane and will not be recreated", instanceID)))
+		}
+		cr.SetConditions(unavailable(reasonInstanceTerminated, stateReason(instance)))
+		return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, nil
+	}
+
+	log.Info("instance terminated outside of crossplane, recreating", "instanceID", instanceID)
+	c.recorder.Event(cr, event.Warning(reasonTerminatedExternally,
+		errors.Errorf("instance %s was terminated outside of Crossplane, launching a new one", instanceID)))
+	cr.SetConditions(xpv1.Creating())
+	return managed.ExternalObservation{ResourceExists: false}, nil
+}
+
+// observeSpotInterruption handles a Spot instance terminated by EC2. Unless
+// the interruption policy only reports it, the instance is relaunched through
+// a replacement that moves the volumes which outlived it to the new instance.
+func (c *external) observeSpotInterruption(ctx context.Context, client *provider.EC2Client, cr *v1alpha1.Compute, instance *ec2types.Instance, volumes []v1alpha1.VolumeAttachment, log logging.Logger) (managed.ExternalObservation, error) {
+	instanceID := aws.ToString(instance.InstanceId)
+	cr.SetConditions(unavailable(reasonSpotInterrupted, stateReason(instance)))
+
+	if o := cr.Spec.ForProvider.InstanceConfig.MarketOptions; o != nil && o.InterruptionPolicy == v1alpha1.InterruptionPolicyReportOnly {
+		log.Info("spot instance interrupted, reporting only", "instanceID", instanceID)
+		c.recorder.Event(cr, event.Warning(reasonSpotInterruption,
+			errors.Errorf("spot instance %s was interrupted and will not be relaunched", instanceID)))
+		return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, nil
+	}
+
+	devices := make(map[string]string, len(volumes))
+	volumeIDs := make([]string, 0, len(volumes))
+	for _, v := range volumes {
+		if v.DeviceName == aws.ToString(instance.RootDeviceName) {
+			continue
+		}
+		devices[v.VolumeID] = v.DeviceName
+		volumeIDs = append(volumeIDs, v.VolumeID)
+	}
+
+	surviving, err := client.SurvivingVolumes(ctx, volumeIDs)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+
+	r := &v1alpha1.Replacement{
+		PreviousInstanceID: instanceID,
+		Strategy:           v1alpha1.ReplacementStrategyRecreate,
+	}
+	for _, volume := range surviving {
+		volumeID := aws.ToString(volume.VolumeId)
+		r.Volumes = append(r.Volumes, v1alpha1.VolumeAttachment{VolumeID: volumeID, DeviceName: devices[volumeID]})
+	}
+	cr.Status.AtProvider.Replacement = r
+
+	log.Info("spot instance interrupted, relaunching", "instanceID", instanceID, "volumes", r.Volumes)
+	c.recorder.Event(cr, event.Warning(reasonSpotInterruption,
+		errors.Errorf("spot instance %s was interrupted, relaunching it with %d volume(s)", instanceID, len(r.Volumes))))
+	return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: false}, nil
+}
+
+// trackVolumes records the volume backing each storage entry the first time
+// it is seen, along with its removal policy. A recorded volume keeps its entry
+// while it is detached, e.g. during a replacement. The entry of a volume
+// removed from the spec is kept until the volume is detached, its removal
+// policy is applied then. It returns the volume state the records were made
+// from.
+func trackVolumes(ctx context.Context, client *provider.EC2Client, cr *v1alpha1.Compute, instance *ec2types.Instance) (*shared.VolumeState, error) {
+	output, err := client.Client.DescribeVolumes(ctx, &ec2.DescribeVolumesInput{
+		Filters: []ec2types.Filter{
+			{Name: aws.String("attachment.instance-id"), Values: []string{aws.ToString(instance.InstanceId)}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	analyzer := shared.NewCommandAnalyzer()
+	state := analyzer.BuildVolumeState(output, instance)
+	state.Desired = cr.Spec.ForProvider.InstanceConfig.Storage
+	state.Tracked = cr.Status.AtProvider.StorageVolumes
+	matches := analyzer.MatchVolumes(state)
+
+	tracked := make(map[string]v1alpha1.StorageVolume, len(state.Tracked))
+	for _, storage := range state.Desired {
+		volume, found := state.Tracked[storage.DeviceName]
+		if vi, matched := matches[storage.DeviceName]; matched {
+			volume.VolumeID = vi.VolumeID
+			found = true
+		}
+
+		if found {
+			volume.RemovalPolicy = storage.RemovalPolicy
+			tracked[storage.DeviceName] = volume
+		}
+	}
+
+	attached := make(map[string]bool, len(state.Current))
+	for _, vi := range state.Current {
+		attached[vi.VolumeID] = true
+	}
+	for deviceName, volume := range state.Tracked {
+		if _, found := tracked[deviceName]; !found && attached[volume.VolumeID] {
+			tracked[deviceName] = volume
+		}
+	}
+
+	if len(tracked) == 0 {
+		tracked = nil
+	}
+	cr.Status.AtProvider.StorageVolumes = tracked
+	state.Tracked = tracked
+	return state, nil
+}
+
+// reportShrinks sets the StorageValid condition. EBS volumes cannot shrink, a
+// smaller size is refused unless the volume can be replaced, in which case
+// the new volume is empty. A Warning event is recorded when a refusal or a
+// replacement first shows up.
+func (c *external) reportShrinks(cr *v1alpha1.Compute, shrinks []shared.VolumeShrink) {
+	var refused, replaced []string
+	for _, shrink := range shrinks {
+		if shrink.Replace {
+			replaced = append(replaced, fmt.Sprintf("volume %s on %s is replaced to shrink it from %dGiB to %dGiB",
+				shrink.VolumeID, shrink.DeviceName, shrink.CurrentSize, shrink.DesiredSize))
+			continue
+		}
+		refused = append(refused, fmt.Sprintf("cannot shrink volume %s on %s from %dGiB to %dGiB",
+			shrink.VolumeID, shrink.DeviceName, shrink.CurrentSize, shrink.DesiredSize))
+	}
+
+	condition := xpv1.Condition{
+		Type:               typeStorageValid,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reasonStorageValid,
+	}
+
+	var messages []string
+	if len(refused) > 0 {
+		messages = append(messages, strings.Join(refused, "; ")+": EBS volumes can only grow, allowReplaceOnShrink replaces a volume other than the root one")
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = reasonVolumeShrinkRefused
+	}
+	if len(replaced) > 0 {
+		messages = append(messages, strings.Join(replaced, "; ")+": the new volume is empty, the previous one is retained along with a snapshot of it")
+		if len(refused) == 0 {
+			condition.Reason = reasonVolumeReplacedOnShrink
+		}
+	}
+	condition.Message = strings.Join(messages, "; ")
+
+	if len(messages) > 0 && !cr.GetCondition(typeStorageValid).Equal(condition) {
+		c.recorder.Event(cr, event.Warning(event.Reason(condition.Reason), errors.New(condition.Message)))
+	}
+	cr.SetConditions(condition)
+}
+
+// planUpdates records the update plan of a Compute whose update policy holds
+// updates back, and reports whether the plan may be applied. A plan keeps its
+// hash, and so its approval, as long as it makes the same changes, the current
+// values it shows are refreshed. A nil plan means the instance is up to date.
+func (c *external) planUpdates(cr *v1alpha1.Compute, plan *v1alpha1.UpdatePlan) bool {
+	policy := cr.Spec.UpdatePolicy
+	if !holdsUpdates(cr) {
+		cr.Status.AtProvider.UpdatePlan = nil
+		if cr.GetCondition(typeUpdatePending).Status != corev1.ConditionUnknown {
+			cr.SetConditions(updatePendingCondition(corev1.ConditionFalse, reasonNoUpdates, ""))
+		}
+		return true
+	}
+
+	if plan == nil {
+		cr.Status.AtProvider.UpdatePlan = nil
+		cr.SetConditions(updatePendingCondition(corev1.ConditionFalse, reasonNoUpdates, ""))
+		return true
+	}
+
+	if existing := cr.Status.AtProvider.UpdatePlan; existing != nil && updater.SamePlan(existing, plan) {
+		existing.Operations = plan.Operations
+		plan = existing
+	} else {
+		plan.CreatedAt = metav1.Now()
+		plan.Hash = updater.PlanHash(plan, plan.CreatedAt)
+		c.recorder.Event(cr, event.Normal(reasonUpdatePlanned, fmt.Sprintf("Update plan %s: %s", plan.Hash, describePlan(plan))))
+	}
+	cr.Status.AtProvider.UpdatePlan = plan
+
+	switch {
+	case policy == v1alpha1.UpdatePolicyReportOnly:
+		cr.SetConditions(updatePendingCondition(corev1.ConditionTrue, reasonReportOnly,
+			fmt.Sprintf("Update plan %s is reported only: %s", plan.Hash, describePlan(plan))))
+		return false
+	case cr.GetAnnotations()[v1alpha1.AnnotationKeyApprovePlan] == plan.Hash:
+		cr.SetConditions(updatePendingCondition(corev1.ConditionFalse, reasonPlanApproved,
+			fmt.Sprintf("Applying update plan %s", plan.Hash)))
+		return true
+	default:
+		cr.SetConditions(updatePendingCondition(corev1.ConditionTrue, reasonAwaitingApproval,
+			fmt.Sprintf("Set the %s annotation to %s to apply: %s", v1alpha1.AnnotationKeyApprovePlan, plan.Hash, describePlan(plan))))
+		return false
+	}
+}
+
+// maintenanceWindowOf returns the maintenance window of the Compute, or the
+// default one of its ProviderConfig, with why it is invalid.
+func (c *external) maintenanceWindowOf(cr *v1alpha1.Compute) (*apisv1alpha1.MaintenanceWindow, error) {
+	if cr.Spec.MaintenanceWindow != nil {
+		return cr.Spec.MaintenanceWindow, updater.ValidateMaintenanceWindow(cr.Spec.MaintenanceWindow)
+	}
+	return c.maintenanceWindow, c.maintenanceErr
+}
+
+// reportDeferral tells through a condition whether updates wait on the next
+// maintenance window, or on an invalid one to be fixed. The condition is only
+// added once updates are deferred.
+func reportDeferral(cr *v1alpha1.Compute, windowErr error) {
+	deferred := cr.Status.AtProvider.DeferredUpdates
+	if deferred == nil {
+		if cr.GetCondition(typeUpdatesDeferred).Status != corev1.ConditionUnknown {
+			cr.SetConditions(xpv1.Condition{
+				Type:               typeUpdatesDeferred,
+				Status:             corev1.ConditionFalse,
+				LastTransitionTime: metav1.Now(),
+				Reason:             reasonNoDeferredUpdates,
+			})
+		}
+		return
+	}
+
+	if windowErr != nil {
+		cr.SetConditions(xpv1.Condition{
+			Type:               typeUpdatesDeferred,
+			Status:             corev1.ConditionTrue,
+			LastTransitionTime: metav1.Now(),
+			Reason:             reasonInvalidMaintenanceWindow,
+			Message: fmt.Sprintf("%s deferred until the maintenance window is fixed: %s",
+				strings.Join(deferred.Operations, ", "), windowErr),
+		})
+		return
+	}
+
+	message := fmt.Sprintf("%s deferred to the next maintenance window", strings.Join(deferred.Operations, ", "))
+	if deferred.NextWindow != nil {
+		message = fmt.Sprintf("%s deferred to the maintenance window opening at %s",
+			strings.Join(deferred.Operations, ", "), deferred.NextWindow.UTC().Format(time.RFC3339))
+	}
+	cr.SetConditions(xpv1.Condition{
+		Type:               typeUpdatesDeferred,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reasonOutsideMaintenanceWindow,
+		Message:            message,
+	})
+}
+
+// holdsUpdates reports whether the update policy of the Compute keeps updates
+// from being applied as soon as they are found.
+func holdsUpdates(cr *v1alpha1.Compute) bool {
+	return cr.Spec.UpdatePolicy != "" && cr.Spec.UpdatePolicy != v1alpha1.UpdatePolicyAutomatic
+}
+
+// approvedUpdates returns the updates the update policy lets through: all of
+// them when automatic, those of the approved plan when manual, none otherwise.
+// Work already in progress is resumed whatever the policy.
+func approvedUpdates(cr *v1alpha1.Compute, updates map[string]bool) map[string]bool {
+	switch cr.Spec.UpdatePolicy {
+	case "", v1alpha1.UpdatePolicyAutomatic:
+		return updates
+	case v1alpha1.UpdatePolicyManual:
+		plan := cr.Status.AtProvider.UpdatePlan
+		if plan == nil || cr.GetAnnotations()[v1alpha1.AnnotationKeyApprovePlan] != plan.Hash {
+			return nil
+		}
+		approved := make(map[string]bool, len(plan.Operations))
+		for _, op := range plan.Operations {
+			approved[op.Type] = updates[op.Type]
+		}
+		return approved
+	}
+
+	return nil
+}
+
+func updatePendingCondition(status corev1.ConditionStatus, reason xpv1.ConditionReason, message string) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               typeUpdatePending,
+		Status:             status,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reason,
+		Message:            message,
+	}
+}
+
+// describePlan summarizes the operations of the plan on a single line.
+func describePlan(plan *v1alpha1.UpdatePlan) string {
+	ops := make([]string, 0, len(plan.Operations))
+	for _, op := range plan.Operations {
+		ops = append(ops, fmt.Sprintf("%s %q -> %q (%s)", op.Type, op.Current, op.Desired, op.Disruption))
+	}
+	return strings.Join(ops, "; ")
+}
+
+// generateKeyPair returns the private key of the key pair generated for the
+// Compute. The key is written to the connection secret before the instance is
+// launched, so a retried creation reuses the key pair it left rather than
+// replacing it.
+func (c *external) generateKeyPair(ctx context.Context, cc *provider.EC2Client, cr *v1alpha1.Compute, keyType v1alpha1.KeyPairType) ([]byte, error) {
+	keyName := provider.GeneratedKeyName(cr)
+	ref := cr.GetWriteConnectionSecretToReference()
+
+	// Without a connection secret the private key is never published, the
+	// key pair is reused whatever its key.
+	var stored []byte
+	if ref != nil {
+		secret := &corev1.Secret{}
+		err := c.kube.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, secret)
+		if resource.IgnoreNotFound(err) != nil {
+			return nil, errors.Wrap(err, errGetConnectionSecret)
+		}
+		stored = secret.Data[keyPrivateKey]
+	}
+
+	if stored != nil || ref == nil {
+		reusable, err := cc.ReusableKeyPair(ctx, keyName, cr.GetUID(), stored)
+		if err != nil {
+			return nil, err
+		}
+		if reusable {
+			return stored, nil
+		}
+	}
+
+	publicKey, privateKey, err := provider.GenerateKeyPair(keyType)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cc.ImportKeyPair(ctx, keyName, publicKey, cr.GetUID()); err != nil {
+		return nil, err
+	}
+
+	if ref == nil {
+		return privateKey, nil
+	}
+
+	secret := resource.ConnectionSecretFor(cr, v1alpha1.ComputeGroupVersionKind)
+	secret.Data = map[string][]byte{keyPrivateKey: privateKey}
+	if err := resource.NewAPIPatchingApplicator(c.kube).Apply(ctx, secret, resource.ConnectionSecretMustBeControllableBy(cr.GetUID())); err != nil {
+		return nil, errors.Wrap(err, errStorePrivateKey)
+	}
+
+	return privateKey, nil
+}
+
+// resolveUserData reads the user data from the Secret or ConfigMap the
+// configuration references, the inline user data taking precedence.
+func (c *external) resolveUserData(ctx context.Context, config *v1alpha1.InstanceConfig) error {
+	if config.UserData != "" {
+		return nil
+	}
+
+	if ref := config.UserDataSecretRef; ref != nil {
+		secret := &corev1.Secret{}
+		if err := c.kube.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, secret); err != nil {
+			return errors.Wrap(err, errGetUserDataSecret)
+		}
+
+		userData, found := secret.Data[ref.Key]
+		if !found {
+			return errors.Errorf(errUserDataKeyNotFound, ref.Key, ref.Namespace, ref.Name)
+		}
+		config.UserData = string(userData)
+		return nil
+	}
+
+	if ref := config.UserDataConfigMapRef; ref != nil {
+		cm := &corev1.ConfigMap{}
+		if err := c.kube.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, cm); err != nil {
+			return errors.Wrap(err, errGetUserDataConfigMap)
+		}
+
+		userData, found := cm.Data[ref.Key]
+		if !found {
+			return errors.Errorf(errUserDataKeyNotFound, ref.Key, ref.Namespace, ref.Name)
+		}
+		config.UserData = userData
+	}
+
+	return nil
+}
+
+// syncExternalName points the external name at the instance recorded in the
+// status when the two diverged, e.g. after the instance was replaced.
+func (c *external) syncExternalName(ctx context.Context, cr *v1alpha1.Compute) error {
+	instanceID := cr.Status.AtProvider.InstanceID
+	if instanceID == "" || meta.GetExternalName(cr) == instanceID {
+		return nil
+	}
+
+	patchCR := cr.DeepCopy()
+	meta.SetExternalName(patchCR, instanceID)
+
+	if err := c.kube.Patch(ctx, patchCR, client.MergeFrom(cr)); err != nil {
+		return errors.Wrap(err, "failed to update compute external name")
+	}
+
+	meta.SetExternalName(cr, instanceID)
+	cr.SetResourceVersion(patchCR.GetResourceVersion())
+	return nil
+}
+
+// connectionDetails returns the details needed to reach the instance. The
+// SSH username and key name are only published when a key pair is in use.
+func connectionDetails(cr *v1alpha1.Compute, instance *ec2types.Instance) managed.ConnectionDetails {
+	details := managed.ConnectionDetails{
+		keyInstanceID: []byte(aws.ToString(instance.InstanceId)),
+	}
+
+	fields := map[string]*string{
+		keyPrivateIP:  instance.PrivateIpAddress,
+		keyPublicIP:   instance.PublicIpAddress,
+		keyPrivateDNS: instance.PrivateDnsName,
+		keyPublicDNS:  instance.PublicDnsName,
+	}
+	for key, value := range fields {
+		if aws.ToString(value) != "" {
+			details[key] = []byte(*value)
+		}
+	}
+
+	switch {
+	case aws.ToString(instance.PublicIpAddress) != "":
+		details[xpv1.ResourceCredentialsSecretEndpointKey] = []byte(*instance.PublicIpAddress)
+	case aws.ToString(instance.PrivateIpAddress) != "":
+		details[xpv1.ResourceCredentialsSecretEndpointKey] = []byte(*instance.PrivateIpAddress)
+	}
+
+	if aws.ToString(instance.KeyName) != "" {
+		username := cr.Spec.ForProvider.InstanceConfig.SSHUsername
+		if username == "" {
+			username = defaultSSHUsername
+		}
+		details[xpv1.ResourceCredentialsSecretUserKey] = []byte(username)
+		details[keyKeyName] = []byte(*instance.KeyName)
+	}
+
+	return details
+}
+
+// instanceCondition maps the lifecycle state of the instance, and the status
+// checks of a running one, to the Ready condition.
+func instanceCondition(instance *ec2types.Instance, status *ec2types.InstanceStatus) xpv1.Condition {
+	switch instance.State.Name {
+	case ec2types.InstanceStateNamePending:
+		return xpv1.Creating()
+	case ec2types.InstanceStateNameShuttingDown, ec2types.InstanceStateNameTerminated:
+		return xpv1.Deleting()
+	case ec2types.InstanceStateNameStopping:
+		return unavailable(reasonInstanceStopping, stateReason(instance))
+	case ec2types.InstanceStateNameStopped:
+		if provider.IsSpotInterruption(instance) {
+			return unavailable(reasonSpotInterrupted, stateReason(instance))
+		}
+		return unavailable(reasonInstanceStopped, stateReason(instance))
+	}
+
+	if status == nil {
+		return xpv1.Available()
+	}
+
+	checks := []ec2types.SummaryStatus{}
+	if status.InstanceStatus != nil {
+		checks = append(checks, status.InstanceStatus.Status)
+	}
+	if status.SystemStatus != nil {
+		checks = append(checks, status.SystemStatus.Status)
+	}
+
+	for _, check := range checks {
+		switch check {
+		case ec2types.SummaryStatusImpaired:
+			return unavailable(reasonInstanceImpaired, "instance status checks report the instance as impaired")
+		case ec2types.SummaryStatusInitializing:
+			c := xpv1.Creating()
+			c.Reason = reasonStatusChecks
+			return c.WithMessage("waiting for the instance status checks to pass")
+		}
+	}
+
+	return xpv1.Available()
+}
+
+func unavailable(reason xpv1.ConditionReason, message string) xpv1.Condition {
+	c := xpv1.Unavailable()
+	c.Reason = reason
+	return c.WithMessage(message)
+}
+
+func stateReason(instance *ec2types.Instance) string {
+	if instance.StateReason == nil {
+		return ""
+	}
+	return aws.ToString(instance.StateReason.Message)
+}
+
+// terminationObserved reports whether the recorded state of the instance shows
+// its termination was already observed.
+func terminationObserved(state string) bool {
+	switch ec2types.InstanceStateName(state) {
+	case ec2types.InstanceStateNameShuttingDown, ec2types.InstanceStateNameTerminated:
+		return true
+	}
+	return false
+}
+
+// isTransitioning reports whether the instance is moving between two states,
+// during which no update can be applied to it.
+func isTransitioning(instance *ec2types.Instance) bool {
+	switch instance.State.Name {
+	case ec2types.InstanceStateNamePending, ec2types.InstanceStateNameStopping, ec2types.InstanceStateNameShuttingDown:
+		return true
+	}
+	return false
+}
+
+// setObservation copies the observed fields of the instance into the status,
+// leaving the fields owned by the controller, such as an ongoing replacement,
+// untouched.
+func setObservation(o *v1alpha1.ComputeObservation, instance *ec2types.Instance) {
+	o.InstanceID = aws.ToString(instance.InstanceId)
+	o.InstanceType = string(instance.InstanceType)
+	o.Architecture = string(instance.Architecture)
+	o.PrivateIP = aws.ToString(instance.PrivateIpAddress)
+	o.PublicIP = aws.ToString(instance.PublicIpAddress)
+	o.PrivateDNS = aws.ToString(instance.PrivateDnsName)
+	o.PublicDNS = aws.ToString(instance.PublicDnsName)
+	o.AvailabilityZone = ""
+	o.LaunchTime = nil
+
+	if instance.State != nil {
+		o.State = string(instance.State.Name)
+	}
+
+	if instance.Placement != nil {
+		o.AvailabilityZone = aws.ToString(instance.Placement.AvailabilityZone)
+	}
+
+	if instance.LaunchTime != nil {
+		launchTime := metav1.NewTime(*instance.LaunchTime)
+		o.LaunchTime = &launchTime
+	}
+
+	o.SecurityGroups = nil
+	for _, sg := range instance.SecurityGroups {
+		o.SecurityGroups = append(o.SecurityGroups, aws.ToString(sg.GroupId))
+	}
+
+	o.Volumes = nil
+	for _, mapping := range instance.BlockDeviceMappings {
+		if mapping.Ebs == nil {
+			continue
+		}
+		o.Volumes = append(o.Volumes, v1alpha1.VolumeAttachment{
+			VolumeID:   aws.ToString(mapping.Ebs.VolumeId),
+			DeviceName: aws.ToString(mapping.DeviceName),
+		})
+	}
 }
 
 func processTags(tags []ec2types.Tag) map[string]string {
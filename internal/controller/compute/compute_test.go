@@ -19,12 +19,33 @@ package compute
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
 	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
 	"github.com/crossplane/crossplane-runtime/pkg/test"
+
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider/fake"
+	"github.com/crossplane/provider-customcomputeprovider/internal/shared"
 )
 
 // Unlike many Kubernetes projects Crossplane does not use third party testing
@@ -72,3 +93,953 @@ func TestObserve(t *testing.T) {
 		})
 	}
 }
+
+func TestSetObservation(t *testing.T) {
+	launchTime := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	type args struct {
+		o        v1alpha1.ComputeObservation
+		instance *ec2types.Instance
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   v1alpha1.ComputeObservation
+	}{
+		"RunningInstance": {
+			reason: "The observed fields of a running instance should be copied into the status.",
+			args: args{
+				instance: &ec2types.Instance{
+					InstanceId:       aws.String("i-0123456789abcdef0"),
+					InstanceType:     ec2types.InstanceTypeT3Micro,
+					Architecture:     ec2types.ArchitectureValuesX8664,
+					State:            &ec2types.InstanceState{Name: ec2types.InstanceStateNameRunning},
+					Placement:        &ec2types.Placement{AvailabilityZone: aws.String("us-east-1a")},
+					PrivateIpAddress: aws.String("10.0.0.10"),
+					PublicIpAddress:  aws.String("54.0.0.10"),
+					PrivateDnsName:   aws.String("ip-10-0-0-10.ec2.internal"),
+					PublicDnsName:    aws.String("ec2-54-0-0-10.compute-1.amazonaws.com"),
+					LaunchTime:       &launchTime,
+					SecurityGroups:   []ec2types.GroupIdentifier{{GroupId: aws.String("sg-1")}},
+					BlockDeviceMappings: []ec2types.InstanceBlockDeviceMapping{
+						{DeviceName: aws.String("/dev/xvda"), Ebs: &ec2types.EbsInstanceBlockDevice{VolumeId: aws.String("vol-1")}},
+					},
+				},
+			},
+			want: v1alpha1.ComputeObservation{
+				InstanceID:       "i-0123456789abcdef0",
+				State:            "running",
+				InstanceType:     "t3.micro",
+				Architecture:     "x86_64",
+				AvailabilityZone: "us-east-1a",
+				PrivateIP:        "10.0.0.10",
+				PublicIP:         "54.0.0.10",
+				PrivateDNS:       "ip-10-0-0-10.ec2.internal",
+				PublicDNS:        "ec2-54-0-0-10.compute-1.amazonaws.com",
+				LaunchTime:       &metav1.Time{Time: launchTime},
+				SecurityGroups:   []string{"sg-1"},
+				Volumes:          []v1alpha1.VolumeAttachment{{VolumeID: "vol-1", DeviceName: "/dev/xvda"}},
+			},
+		},
+		"KeepControllerFields": {
+			reason: "Fields owned by the controller should survive an observation, stale observed fields should not.",
+			args: args{
+				o: v1alpha1.ComputeObservation{
+					PublicIP:    "54.0.0.10",
+					Replacement: &v1alpha1.Replacement{PreviousInstanceID: "i-old", Phase: "Launch"},
+				},
+				instance: &ec2types.Instance{
+					InstanceId: aws.String("i-new"),
+					State:      &ec2types.InstanceState{Name: ec2types.InstanceStateNameStopped},
+				},
+			},
+			want: v1alpha1.ComputeObservation{
+				InstanceID:  "i-new",
+				State:       "stopped",
+				Replacement: &v1alpha1.Replacement{PreviousInstanceID: "i-old", Phase: "Launch"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := tc.args.o
+			setObservation(&got, tc.args.instance)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nsetObservation(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestConnectionDetails(t *testing.T) {
+	type args struct {
+		cr       *v1alpha1.Compute
+		instance *ec2types.Instance
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   managed.ConnectionDetails
+	}{
+		"PendingInstance": {
+			reason: "Only the instance ID should be published before any address is assigned.",
+			args: args{
+				cr:       &v1alpha1.Compute{},
+				instance: &ec2types.Instance{InstanceId: aws.String("i-1")},
+			},
+			want: managed.ConnectionDetails{
+				"instanceID": []byte("i-1"),
+			},
+		},
+		"PrivateInstanceWithKeyPair": {
+			reason: "A private instance launched with a key pair should publish its private endpoint and SSH access.",
+			args: args{
+				cr: &v1alpha1.Compute{
+					Spec: v1alpha1.ComputeSpec{ForProvider: v1alpha1.ComputeParameters{
+						InstanceConfig: v1alpha1.InstanceConfig{SSHUsername: "ubuntu"},
+					}},
+				},
+				instance: &ec2types.Instance{
+					InstanceId:       aws.String("i-1"),
+					PrivateIpAddress: aws.String("10.0.0.10"),
+					PrivateDnsName:   aws.String("ip-10-0-0-10.ec2.internal"),
+					PublicDnsName:    aws.String(""),
+					KeyName:          aws.String("ops"),
+				},
+			},
+			want: managed.ConnectionDetails{
+				"instanceID": []byte("i-1"),
+				"privateIP":  []byte("10.0.0.10"),
+				"privateDNS": []byte("ip-10-0-0-10.ec2.internal"),
+				"endpoint":   []byte("10.0.0.10"),
+				"username":   []byte("ubuntu"),
+				"keyName":    []byte("ops"),
+			},
+		},
+		"PublicInstance": {
+			reason: "The public address should be preferred as the endpoint.",
+			args: args{
+				cr: &v1alpha1.Compute{},
+				instance: &ec2types.Instance{
+					InstanceId:       aws.String("i-1"),
+					PrivateIpAddress: aws.String("10.0.0.10"),
+					PublicIpAddress:  aws.String("54.0.0.10"),
+				},
+			},
+			want: managed.ConnectionDetails{
+				"instanceID": []byte("i-1"),
+				"privateIP":  []byte("10.0.0.10"),
+				"publicIP":   []byte("54.0.0.10"),
+				"endpoint":   []byte("54.0.0.10"),
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := connectionDetails(tc.args.cr, tc.args.instance)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nconnectionDetails(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestInstanceCondition(t *testing.T) {
+	stopped := xpv1.Unavailable()
+	stopped.Reason = reasonInstanceStopped
+	stopped = stopped.WithMessage("Client.UserInitiatedShutdown: User initiated shutdown")
+
+	interrupted := xpv1.Unavailable()
+	interrupted.Reason = reasonSpotInterrupted
+	interrupted = interrupted.WithMessage("Server.SpotInstanceShutdown: Stopped due to Spot capacity")
+
+	impaired := xpv1.Unavailable()
+	impaired.Reason = reasonInstanceImpaired
+	impaired = impaired.WithMessage("instance status checks report the instance as impaired")
+
+	type args struct {
+		instance *ec2types.Instance
+		status   *ec2types.InstanceStatus
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   xpv1.Condition
+	}{
+		"Pending": {
+			reason: "A pending instance is being created.",
+			args: args{
+				instance: &ec2types.Instance{State: &ec2types.InstanceState{Name: ec2types.InstanceStateNamePending}},
+			},
+			want: xpv1.Creating(),
+		},
+		"Running": {
+			reason: "A running instance with passing status checks is available.",
+			args: args{
+				instance: &ec2types.Instance{State: &ec2types.InstanceState{Name: ec2types.InstanceStateNameRunning}},
+				status: &ec2types.InstanceStatus{
+					InstanceStatus: &ec2types.InstanceStatusSummary{Status: ec2types.SummaryStatusOk},
+					SystemStatus:   &ec2types.InstanceStatusSummary{Status: ec2types.SummaryStatusOk},
+				},
+			},
+			want: xpv1.Available(),
+		},
+		"Impaired": {
+			reason: "A running instance failing its status checks is unavailable.",
+			args: args{
+				instance: &ec2types.Instance{State: &ec2types.InstanceState{Name: ec2types.InstanceStateNameRunning}},
+				status: &ec2types.InstanceStatus{
+					InstanceStatus: &ec2types.InstanceStatusSummary{Status: ec2types.SummaryStatusImpaired},
+				},
+			},
+			want: impaired,
+		},
+		"Stopped": {
+			reason: "A stopped instance is unavailable and carries the reason EC2 reports.",
+			args: args{
+				instance: &ec2types.Instance{
+					State:       &ec2types.InstanceState{Name: ec2types.InstanceStateNameStopped},
+					StateReason: &ec2types.StateReason{Message: aws.String("Client.UserInitiatedShutdown: User initiated shutdown")},
+				},
+			},
+			want: stopped,
+		},
+		"SpotInterrupted": {
+			reason: "A Spot instance stopped by EC2 is reported as interrupted.",
+			args: args{
+				instance: &ec2types.Instance{
+					State:             &ec2types.InstanceState{Name: ec2types.InstanceStateNameStopped},
+					InstanceLifecycle: ec2types.InstanceLifecycleTypeSpot,
+					StateReason: &ec2types.StateReason{
+						Code:    aws.String("Server.SpotInstanceShutdown"),
+						Message: aws.String("Server.SpotInstanceShutdown: Stopped due to Spot capacity"),
+					},
+				},
+			},
+			want: interrupted,
+		},
+		"ShuttingDown": {
+			reason: "An instance shutting down is being deleted.",
+			args: args{
+				instance: &ec2types.Instance{State: &ec2types.InstanceState{Name: ec2types.InstanceStateNameShuttingDown}},
+			},
+			want: xpv1.Deleting(),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := instanceCondition(tc.args.instance, tc.args.status)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ninstanceCondition(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestPollInterval(t *testing.T) {
+	cases := map[string]struct {
+		reason   string
+		cr       *v1alpha1.Compute
+		interval time.Duration
+		want     time.Duration
+	}{
+		"Idle": {
+			reason:   "A Compute without work in progress should keep the configured poll interval.",
+			cr:       &v1alpha1.Compute{},
+			interval: time.Minute,
+			want:     time.Minute,
+		},
+		"PendingOperation": {
+			reason: "A Compute with a pending operation should be polled more often.",
+			cr: &v1alpha1.Compute{Status: v1alpha1.ComputeStatus{AtProvider: v1alpha1.ComputeObservation{
+				PendingOperation: &v1alpha1.PendingOperation{Operations: []string{"InstanceType"}},
+			}}},
+			interval: time.Minute,
+			want:     operationPollInterval,
+		},
+		"VolumeRemovals": {
+			reason: "A Compute with volumes waiting on their removal policy should be polled more often.",
+			cr: &v1alpha1.Compute{Status: v1alpha1.ComputeStatus{AtProvider: v1alpha1.ComputeObservation{
+				VolumeRemovals: []v1alpha1.VolumeRemoval{{VolumeID: "vol-1", RemovalPolicy: v1alpha1.RemovalPolicySnapshot}},
+			}}},
+			interval: time.Minute,
+			want:     operationPollInterval,
+		},
+		"ShortInterval": {
+			reason: "A poll interval already shorter than the operation one should be kept.",
+			cr: &v1alpha1.Compute{Status: v1alpha1.ComputeStatus{AtProvider: v1alpha1.ComputeObservation{
+				Replacement: &v1alpha1.Replacement{PreviousInstanceID: "i-old"},
+			}}},
+			interval: 5 * time.Second,
+			want:     5 * time.Second,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := pollInterval(tc.cr, tc.interval)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\npollInterval(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestResolveUserData(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	type args struct {
+		kube   client.Client
+		config v1alpha1.InstanceConfig
+	}
+
+	type want struct {
+		userData string
+		err      error
+	}
+
+	cases := map[string]struct {
+		reason string
+		args   args
+		want   want
+	}{
+		"Inline": {
+			reason: "Inline user data should take precedence over any reference.",
+			args: args{
+				kube: &test.MockClient{MockGet: test.NewMockGetFn(errBoom)},
+				config: v1alpha1.InstanceConfig{
+					UserData:          "#cloud-config",
+					UserDataSecretRef: &xpv1.SecretKeySelector{Key: "userData"},
+				},
+			},
+			want: want{userData: "#cloud-config"},
+		},
+		"Secret": {
+			reason: "User data should be read from the referenced Secret key.",
+			args: args{
+				kube: &test.MockClient{MockGet: test.NewMockGetFn(nil, func(obj client.Object) error {
+					obj.(*corev1.Secret).Data = map[string][]byte{"userData": []byte("#!/bin/bash")}
+					return nil
+				})},
+				config: v1alpha1.InstanceConfig{
+					UserDataSecretRef: &xpv1.SecretKeySelector{
+						SecretReference: xpv1.SecretReference{Name: "bootstrap", Namespace: "default"},
+						Key:             "userData",
+					},
+				},
+			},
+			want: want{userData: "#!/bin/bash"},
+		},
+		"ConfigMapKeyNotFound": {
+			reason: "A missing ConfigMap key should be reported.",
+			args: args{
+				kube: &test.MockClient{MockGet: test.NewMockGetFn(nil)},
+				config: v1alpha1.InstanceConfig{
+					UserDataConfigMapRef: &v1alpha1.ConfigMapKeySelector{Name: "bootstrap", Namespace: "default", Key: "userData"},
+				},
+			},
+			want: want{err: errors.Errorf(errUserDataKeyNotFound, "userData", "default", "bootstrap")},
+		},
+		"SecretGetError": {
+			reason: "Errors getting the Secret should be returned.",
+			args: args{
+				kube: &test.MockClient{MockGet: test.NewMockGetFn(errBoom)},
+				config: v1alpha1.InstanceConfig{
+					UserDataSecretRef: &xpv1.SecretKeySelector{Key: "userData"},
+				},
+			},
+			want: want{err: errors.Wrap(errBoom, errGetUserDataSecret)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{kube: tc.args.kube}
+			err := e.resolveUserData(context.Background(), &tc.args.config)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.resolveUserData(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.userData, tc.args.config.UserData); diff != "" {
+				t.Errorf("\n%s\ne.resolveUserData(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestReportShrinks(t *testing.T) {
+	refused := "cannot shrink volume vol-1 on /dev/sdf from 100GiB to 50GiB: EBS volumes can only grow, allowReplaceOnShrink replaces a volume other than the root one"
+	replaced := "volume vol-2 on /dev/sdg is replaced to shrink it from 100GiB to 50GiB: the new volume is empty, the previous one is retained along with a snapshot of it"
+
+	cases := map[string]struct {
+		reason  string
+		shrinks []shared.VolumeShrink
+		want    xpv1.Condition
+	}{
+		"NoShrink": {
+			reason: "Storage without a smaller size should be valid.",
+			want: xpv1.Condition{
+				Type:   typeStorageValid,
+				Status: corev1.ConditionTrue,
+				Reason: reasonStorageValid,
+			},
+		},
+		"Replaced": {
+			reason: "A volume replaced to shrink it should not be refused, the new volume being empty should be reported.",
+			shrinks: []shared.VolumeShrink{
+				{DeviceName: "/dev/sdg", VolumeID: "vol-2", CurrentSize: 100, DesiredSize: 50, Replace: true},
+			},
+			want: xpv1.Condition{
+				Type:    typeStorageValid,
+				Status:  corev1.ConditionTrue,
+				Reason:  reasonVolumeReplacedOnShrink,
+				Message: replaced,
+			},
+		},
+		"RefusedAndReplaced": {
+			reason: "A refused shrink should fail the condition while still reporting the replaced volume.",
+			shrinks: []shared.VolumeShrink{
+				{DeviceName: "/dev/sdf", VolumeID: "vol-1", CurrentSize: 100, DesiredSize: 50},
+				{DeviceName: "/dev/sdg", VolumeID: "vol-2", CurrentSize: 100, DesiredSize: 50, Replace: true},
+			},
+			want: xpv1.Condition{
+				Type:    typeStorageValid,
+				Status:  corev1.ConditionFalse,
+				Reason:  reasonVolumeShrinkRefused,
+				Message: refused + "; " + replaced,
+			},
+		},
+		"Refused": {
+			reason: "A shrink that cannot be applied should name the device and both sizes.",
+			shrinks: []shared.VolumeShrink{
+				{DeviceName: "/dev/sdf", VolumeID: "vol-1", CurrentSize: 100, DesiredSize: 50},
+			},
+			want: xpv1.Condition{
+				Type:    typeStorageValid,
+				Status:  corev1.ConditionFalse,
+				Reason:  reasonVolumeShrinkRefused,
+				Message: refused,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cr := &v1alpha1.Compute{}
+			e := &external{recorder: event.NewNopRecorder()}
+			e.reportShrinks(cr, tc.shrinks)
+
+			got := cr.GetCondition(typeStorageValid)
+			if !tc.want.Equal(got) {
+				t.Errorf("\n%s\nreportShrinks(...): want %+v, got %+v\n", tc.reason, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPlanUpdates(t *testing.T) {
+	plan := func() *v1alpha1.UpdatePlan {
+		return &v1alpha1.UpdatePlan{
+			Operations: []v1alpha1.PlannedOperation{
+				{Type: "InstanceType", Disruption: v1alpha1.DisruptionRestart, Current: "t3.micro", Desired: "t3.large"},
+			},
+			Disruption: v1alpha1.DisruptionRestart,
+		}
+	}
+	recorded := plan()
+	recorded.Hash = "0123456789abcdef"
+
+	type want struct {
+		apply  bool
+		reason xpv1.ConditionReason
+		hash   string
+	}
+
+	cases := map[string]struct {
+		reason      string
+		policy      v1alpha1.UpdatePolicy
+		annotations map[string]string
+		recorded    *v1alpha1.UpdatePlan
+		plan        *v1alpha1.UpdatePlan
+		want        want
+	}{
+		"Automatic": {
+			reason: "An automatic update policy should apply the updates without a plan.",
+			want:   want{apply: true},
+		},
+		"ReportOnly": {
+			reason: "A report only update policy should record the plan and never apply it.",
+			policy: v1alpha1.UpdatePolicyReportOnly,
+			plan:   plan(),
+			want:   want{apply: false, reason: reasonReportOnly},
+		},
+		"AwaitingApproval": {
+			reason: "A manual update policy should wait for the approval of the plan.",
+			policy: v1alpha1.UpdatePolicyManual,
+			plan:   plan(),
+			want:   want{apply: false, reason: reasonAwaitingApproval},
+		},
+		"Approved": {
+			reason:      "A manual update policy should apply the plan whose hash is approved.",
+			policy:      v1alpha1.UpdatePolicyManual,
+			annotations: map[string]string{v1alpha1.AnnotationKeyApprovePlan: recorded.Hash},
+			recorded:    recorded,
+			plan:        plan(),
+			want:        want{apply: true, reason: reasonPlanApproved, hash: recorded.Hash},
+		},
+		"CurrentChanged": {
+			reason:      "An approval should hold while only the current values of the plan follow the instance.",
+			policy:      v1alpha1.UpdatePolicyManual,
+			annotations: map[string]string{v1alpha1.AnnotationKeyApprovePlan: recorded.Hash},
+			recorded:    recorded,
+			plan: &v1alpha1.UpdatePlan{Operations: []v1alpha1.PlannedOperation{
+				{Type: "InstanceType", Disruption: v1alpha1.DisruptionRestart, Current: "t3.small", Desired: "t3.large"},
+			}},
+			want: want{apply: true, reason: reasonPlanApproved, hash: recorded.Hash},
+		},
+		"ChangedPlan": {
+			reason:      "An approval should not carry over to a plan with different operations.",
+			policy:      v1alpha1.UpdatePolicyManual,
+			annotations: map[string]string{v1alpha1.AnnotationKeyApprovePlan: recorded.Hash},
+			recorded:    recorded,
+			plan: &v1alpha1.UpdatePlan{Operations: []v1alpha1.PlannedOperation{
+				{Type: "InstanceType", Disruption: v1alpha1.DisruptionRestart, Current: "t3.micro", Desired: "t3.xlarge"},
+			}},
+			want: want{apply: false, reason: reasonAwaitingApproval},
+		},
+		"UpToDate": {
+			reason:   "A manual update policy without updates should clear the plan.",
+			policy:   v1alpha1.UpdatePolicyManual,
+			recorded: recorded,
+			want:     want{apply: true, reason: reasonNoUpdates},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cr := &v1alpha1.Compute{
+				ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations},
+				Spec:       v1alpha1.ComputeSpec{UpdatePolicy: tc.policy},
+				Status:     v1alpha1.ComputeStatus{AtProvider: v1alpha1.ComputeObservation{UpdatePlan: tc.recorded.DeepCopy()}},
+			}
+			e := &external{recorder: event.NewNopRecorder()}
+
+			got := want{
+				apply:  e.planUpdates(cr, tc.plan),
+				reason: cr.GetCondition(typeUpdatePending).Reason,
+			}
+			if tc.want.hash != "" && cr.Status.AtProvider.UpdatePlan != nil {
+				got.hash = cr.Status.AtProvider.UpdatePlan.Hash
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nplanUpdates(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+
+			if tc.plan == nil && cr.Status.AtProvider.UpdatePlan != nil {
+				t.Errorf("\n%s\nplanUpdates(...): want no plan, got %+v\n", tc.reason, cr.Status.AtProvider.UpdatePlan)
+			}
+		})
+	}
+}
+
+// recorder counts the events recorded on a resource.
+type recorder struct {
+	events []event.Event
+}
+
+func (r *recorder) Event(_ runtime.Object, e event.Event) { r.events = append(r.events, e) }
+
+func (r *recorder) WithAnnotations(_ ...string) event.Recorder { return r }
+
+func TestObserveTerminated(t *testing.T) {
+	vanished := &ec2types.Instance{
+		InstanceId: aws.String("i-1"),
+		State:      &ec2types.InstanceState{Name: ec2types.InstanceStateNameTerminated},
+	}
+	launchTime := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	terminated := &ec2types.Instance{
+		InstanceId: aws.String("i-1"),
+		LaunchTime: &launchTime,
+		State:      &ec2types.InstanceState{Name: ec2types.InstanceStateNameTerminated},
+	}
+
+	type want struct {
+		o      managed.ExternalObservation
+		events int
+		state  string
+		ip     string
+	}
+
+	cases := map[string]struct {
+		reason   string
+		policy   v1alpha1.RecreatePolicy
+		status   v1alpha1.ComputeObservation
+		instance *ec2types.Instance
+		want     want
+	}{
+		"ReportOnlyFirstObserved": {
+			reason:   "A termination under a report only policy should be reported when first observed.",
+			policy:   v1alpha1.RecreatePolicyReportOnly,
+			status:   v1alpha1.ComputeObservation{InstanceID: "i-1", State: "running", PrivateIP: "10.0.0.1"},
+			instance: terminated,
+			want: want{
+				o:      managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				events: 1,
+				state:  "terminated",
+			},
+		},
+		"ReportOnlyAlreadyObserved": {
+			reason:   "A termination under a report only policy should not be reported on every poll.",
+			policy:   v1alpha1.RecreatePolicyReportOnly,
+			status:   v1alpha1.ComputeObservation{InstanceID: "i-1", State: "terminated"},
+			instance: terminated,
+			want: want{
+				o:     managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				state: "terminated",
+			},
+		},
+		"ReportOnlyVanished": {
+			reason:   "An instance EC2 no longer describes should not be recreated under a report only policy.",
+			policy:   v1alpha1.RecreatePolicyReportOnly,
+			status:   v1alpha1.ComputeObservation{InstanceID: "i-1", State: "terminated", PrivateIP: "10.0.0.1"},
+			instance: vanished,
+			want: want{
+				o:     managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true},
+				state: "terminated",
+				ip:    "10.0.0.1",
+			},
+		},
+		"RecreateVanished": {
+			reason:   "An instance EC2 no longer describes should be recreated under a recreate policy.",
+			policy:   v1alpha1.RecreatePolicyRecreate,
+			status:   v1alpha1.ComputeObservation{InstanceID: "i-1", State: "running", PrivateIP: "10.0.0.1"},
+			instance: vanished,
+			want: want{
+				o:      managed.ExternalObservation{ResourceExists: false},
+				events: 1,
+				state:  "terminated",
+				ip:     "10.0.0.1",
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cr := &v1alpha1.Compute{
+				Spec:   v1alpha1.ComputeSpec{ForProvider: v1alpha1.ComputeParameters{RecreatePolicy: tc.policy}},
+				Status: v1alpha1.ComputeStatus{AtProvider: tc.status},
+			}
+			r := &recorder{}
+			e := &external{recorder: r}
+
+			o, err := e.observeTerminated(context.Background(), nil, cr, tc.instance, logging.NewNopLogger())
+			if err != nil {
+				t.Fatalf("observeTerminated(...): %v", err)
+			}
+
+			got := want{o: o, events: len(r.events), state: cr.Status.AtProvider.State, ip: cr.Status.AtProvider.PrivateIP}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nobserveTerminated(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestSyncExternalName(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	type want struct {
+		err          bool
+		externalName string
+		patched      bool
+	}
+
+	cases := map[string]struct {
+		reason       string
+		externalName string
+		instanceID   string
+		patchErr     error
+		want         want
+	}{
+		"Diverged": {
+			reason:       "The external name should follow the instance recorded in the status, e.g. after a replacement.",
+			externalName: "i-previous",
+			instanceID:   "i-new",
+			want:         want{externalName: "i-new", patched: true},
+		},
+		"Default": {
+			reason:       "The default external name should be replaced by the instance ID once it is known.",
+			externalName: "web",
+			instanceID:   "i-new",
+			want:         want{externalName: "i-new", patched: true},
+		},
+		"InSync": {
+			reason:       "An external name already naming the instance should not be patched.",
+			externalName: "i-new",
+			instanceID:   "i-new",
+			want:         want{externalName: "i-new"},
+		},
+		"NoInstance": {
+			reason:       "The external name should be left alone until an instance is recorded.",
+			externalName: "web",
+			want:         want{externalName: "web"},
+		},
+		"PatchError": {
+			reason:       "A failed patch should be returned and leave the external name as it was.",
+			externalName: "i-previous",
+			instanceID:   "i-new",
+			patchErr:     errBoom,
+			want:         want{err: true, externalName: "i-previous", patched: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cr := &v1alpha1.Compute{Status: v1alpha1.ComputeStatus{AtProvider: v1alpha1.ComputeObservation{InstanceID: tc.instanceID}}}
+			meta.SetExternalName(cr, tc.externalName)
+
+			patched := false
+			e := &external{kube: &test.MockClient{
+				MockPatch: func(_ context.Context, obj client.Object, _ client.Patch, _ ...client.PatchOption) error {
+					patched = true
+					if meta.GetExternalName(obj) != tc.instanceID {
+						t.Errorf("Patch(...): want external name %s, got %s", tc.instanceID, meta.GetExternalName(obj))
+					}
+					return tc.patchErr
+				},
+			}}
+
+			err := e.syncExternalName(context.Background(), cr)
+
+			got := want{err: err != nil, externalName: meta.GetExternalName(cr), patched: patched}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nsyncExternalName(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	type want struct {
+		terminated     []string
+		keyPairDeleted bool
+		deleting       bool
+		err            bool
+	}
+
+	cases := map[string]struct {
+		reason    string
+		status    v1alpha1.ComputeObservation
+		keyPair   bool
+		instances []ec2types.Instance
+		want      want
+	}{
+		"Running": {
+			reason:    "A running instance should be terminated.",
+			status:    v1alpha1.ComputeObservation{InstanceID: "i-1"},
+			instances: []ec2types.Instance{fake.Instance("i-1", ec2types.InstanceStateNameRunning)},
+			want:      want{terminated: []string{"i-1"}, deleting: true},
+		},
+		"AlreadyTerminated": {
+			reason:    "An instance already terminated should not be terminated again.",
+			status:    v1alpha1.ComputeObservation{InstanceID: "i-1"},
+			instances: []ec2types.Instance{fake.Instance("i-1", ec2types.InstanceStateNameTerminated)},
+			want:      want{deleting: true},
+		},
+		"NotFound": {
+			reason: "An instance EC2 no longer knows should be taken as deleted.",
+			status: v1alpha1.ComputeObservation{InstanceID: "i-1"},
+			want:   want{deleting: true},
+		},
+		"PreviousInstanceLeftRunning": {
+			reason: "The previous instance of an interrupted replacement should be terminated along with the new one.",
+			status: v1alpha1.ComputeObservation{
+				InstanceID:  "i-2",
+				Replacement: &v1alpha1.Replacement{PreviousInstanceID: "i-1"},
+			},
+			instances: []ec2types.Instance{
+				fake.Instance("i-1", ec2types.InstanceStateNameRunning),
+				fake.Instance("i-2", ec2types.InstanceStateNameRunning),
+			},
+			want: want{terminated: []string{"i-2", "i-1"}, deleting: true},
+		},
+		"PreviousInstanceTerminated": {
+			reason: "The previous instance of an interrupted replacement already terminated should be left alone.",
+			status: v1alpha1.ComputeObservation{
+				InstanceID:  "i-2",
+				Replacement: &v1alpha1.Replacement{PreviousInstanceID: "i-1"},
+			},
+			instances: []ec2types.Instance{
+				fake.Instance("i-1", ec2types.InstanceStateNameTerminated),
+				fake.Instance("i-2", ec2types.InstanceStateNameRunning),
+			},
+			want: want{terminated: []string{"i-2"}, deleting: true},
+		},
+		"GeneratedKeyPair": {
+			reason:    "The key pair generated for the instance should be deleted along with it.",
+			status:    v1alpha1.ComputeObservation{InstanceID: "i-1"},
+			keyPair:   true,
+			instances: []ec2types.Instance{fake.Instance("i-1", ec2types.InstanceStateNameRunning)},
+			want:      want{terminated: []string{"i-1"}, keyPairDeleted: true, deleting: true},
+		},
+		"PreviousInstanceIsCurrent": {
+			reason: "A replacement that has not launched yet should not terminate its instance twice.",
+			status: v1alpha1.ComputeObservation{
+				InstanceID:  "i-1",
+				Replacement: &v1alpha1.Replacement{PreviousInstanceID: "i-1"},
+			},
+			instances: []ec2types.Instance{fake.Instance("i-1", ec2types.InstanceStateNameRunning)},
+			want:      want{terminated: []string{"i-1"}, deleting: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var terminated []string
+			fakeEC2 := &fake.EC2{Outputs: map[string]fake.Output{
+				"DescribeInstances": func(input interface{}) (interface{}, error) {
+					output, _ := fake.DescribeInstances(tc.instances...)(input)
+					if len(output.(*ec2.DescribeInstancesOutput).Reservations) == 0 {
+						return nil, &smithy.GenericAPIError{Code: "InvalidInstanceID.NotFound"}
+					}
+					return output, nil
+				},
+				"TerminateInstances": func(input interface{}) (interface{}, error) {
+					terminated = append(terminated, input.(*ec2.TerminateInstancesInput).InstanceIds...)
+					return &ec2.TerminateInstancesOutput{}, nil
+				},
+				"DeleteKeyPair": fake.Answer(&ec2.DeleteKeyPairOutput{}),
+			}}
+			cr := &v1alpha1.Compute{Status: v1alpha1.ComputeStatus{AtProvider: tc.status}}
+			if tc.keyPair {
+				cr.Spec.ForProvider.InstanceConfig.KeyPairGeneration = &v1alpha1.KeyPairGeneration{}
+			}
+			e := &external{
+				service: &provider.EC2Client{Client: fakeEC2.Client()},
+				logger:  logging.NewNopLogger(),
+			}
+
+			err := e.Delete(context.Background(), cr)
+
+			got := want{
+				terminated:     terminated,
+				keyPairDeleted: fakeEC2.Calls[len(fakeEC2.Calls)-1] == "DeleteKeyPair",
+				deleting:       cr.GetCondition(xpv1.TypeReady).Reason == xpv1.ReasonDeleting,
+				err:            err != nil,
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nDelete(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestGenerateKeyPair(t *testing.T) {
+	public, private, err := provider.GenerateKeyPair(v1alpha1.KeyPairTypeED25519)
+	if err != nil {
+		t.Fatalf("provider.GenerateKeyPair(...): %v", err)
+	}
+	_, other, err := provider.GenerateKeyPair(v1alpha1.KeyPairTypeED25519)
+	if err != nil {
+		t.Fatalf("provider.GenerateKeyPair(...): %v", err)
+	}
+
+	owned := fake.Answer(&ec2.DescribeKeyPairsOutput{KeyPairs: []ec2types.KeyPairInfo{{
+		KeyName:   aws.String("crossplane-compute-uid"),
+		PublicKey: aws.String(string(public)),
+		Tags:      []ec2types.Tag{{Key: aws.String(provider.OwnerTagKey), Value: aws.String("compute-uid")}},
+	}}})
+
+	// storedSecret holds the private key given in the connection secret.
+	storedSecret := func(key []byte) test.MockGetFn {
+		return test.NewMockGetFn(nil, func(obj client.Object) error {
+			s := obj.(*corev1.Secret)
+			s.Type = resource.SecretTypeConnection
+			s.Data = map[string][]byte{keyPrivateKey: key}
+			return nil
+		})
+	}
+	notFound := test.NewMockGetFn(kerrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "compute"))
+
+	type want struct {
+		reused  bool
+		calls   []string
+		written bool
+		err     bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		ref    *xpv1.SecretReference
+		get    test.MockGetFn
+		want   want
+	}{
+		"ReuseStored": {
+			reason: "The key pair left by a previous attempt should be reused when it matches the stored private key.",
+			ref:    &xpv1.SecretReference{Name: "compute", Namespace: "default"},
+			get:    storedSecret(private),
+			want:   want{reused: true, calls: []string{"DescribeKeyPairs"}},
+		},
+		"ReuseWithoutSecret": {
+			reason: "The owned key pair should be reused when the private key is never published.",
+			want:   want{reused: true, calls: []string{"DescribeKeyPairs"}},
+		},
+		"StoredMismatch": {
+			reason: "A key pair not matching the stored private key should be replaced and the new key stored.",
+			ref:    &xpv1.SecretReference{Name: "compute", Namespace: "default"},
+			get:    storedSecret(other),
+			want:   want{calls: []string{"DescribeKeyPairs", "DeleteKeyPair", "ImportKeyPair"}, written: true},
+		},
+		"NoStoredKey": {
+			reason: "A key pair whose private key was never stored should be replaced and the new key stored.",
+			ref:    &xpv1.SecretReference{Name: "compute", Namespace: "default"},
+			get:    notFound,
+			want:   want{calls: []string{"DeleteKeyPair", "ImportKeyPair"}, written: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fakeEC2 := &fake.EC2{Outputs: map[string]fake.Output{
+				"DescribeKeyPairs": owned,
+				"DeleteKeyPair":    fake.Answer(&ec2.DeleteKeyPairOutput{}),
+				"ImportKeyPair":    fake.Answer(&ec2.ImportKeyPairOutput{}),
+			}}
+			written := false
+			kube := &test.MockClient{
+				MockGet: tc.get,
+				MockCreate: func(_ context.Context, _ client.Object, _ ...client.CreateOption) error {
+					written = true
+					return nil
+				},
+				MockPatch: func(_ context.Context, _ client.Object, _ client.Patch, _ ...client.PatchOption) error {
+					written = true
+					return nil
+				},
+			}
+			cr := &v1alpha1.Compute{}
+			cr.SetName("compute")
+			cr.SetUID("compute-uid")
+			cr.SetWriteConnectionSecretToReference(tc.ref)
+			e := &external{kube: kube}
+
+			key, err := e.generateKeyPair(context.Background(), &provider.EC2Client{Client: fakeEC2.Client()}, cr, v1alpha1.KeyPairTypeED25519)
+
+			reused := string(key) == string(private)
+			if tc.ref == nil {
+				// Without a secret there is no stored key, a reused key
+				// pair returns none.
+				reused = key == nil
+			}
+			got := want{reused: reused, calls: fakeEC2.Calls, written: written, err: err != nil}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\ngenerateKeyPair(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
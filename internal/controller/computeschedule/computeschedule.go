@@ -0,0 +1,440 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package computeschedule
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/pkg/ratelimiter"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+)
+
+const (
+	errNotComputeSchedule = "managed resource is not a ComputeSchedule custom resource"
+	errParseSchedule      = "cannot parse schedule"
+	errComputeSelector    = "cannot parse compute selector"
+	errListComputes       = "cannot list computes"
+	errPatchCompute       = "cannot patch compute %s"
+)
+
+// transitionDelay is how long after a transition the schedule is reconciled,
+// so the transition is behind it by then.
+const transitionDelay = time.Second
+
+// typeComputesClaimed tells whether selected Computes are left to another
+// schedule.
+const (
+	typeComputesClaimed    xpv1.ConditionType   = "ComputesClaimed"
+	reasonClaimedElsewhere xpv1.ConditionReason = "ClaimedByAnotherSchedule"
+	reasonNoClaims         xpv1.ConditionReason = "NoClaims"
+)
+
+// Reasons of the events recorded for a ComputeSchedule and its Computes.
+const (
+	reasonPowerStateScheduled event.Reason = "PowerStateScheduled"
+	reasonComputeClaimed      event.Reason = "ComputeClaimed"
+)
+
+// Setup adds a controller that reconciles ComputeSchedule managed resources.
+// A schedule only ever talks to the API server, it neither needs credentials
+// nor uses its ProviderConfig.
+func Setup(mgr ctrl.Manager, o controller.Options) error {
+	name := managed.ControllerName(v1alpha1.ComputeScheduleGroupKind)
+
+	recorder := event.NewAPIRecorder(mgr.GetEventRecorderFor(name))
+
+	r := managed.NewReconciler(mgr,
+		resource.ManagedKind(v1alpha1.ComputeScheduleGroupVersionKind),
+		managed.WithExternalConnecter(&connector{
+			kube:     mgr.GetClient(),
+			logger:   o.Logger,
+			recorder: recorder,
+		}),
+		managed.WithLogger(o.Logger.WithValues("controller", name)),
+		managed.WithPollInterval(o.PollInterval),
+		managed.WithPollIntervalHook(pollInterval),
+		managed.WithRecorder(recorder))
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		WithEventFilter(resource.DesiredStateChanged()).
+		For(&v1alpha1.ComputeSchedule{}).
+		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+}
+
+// pollInterval wakes the schedule up right after its next transition when it
+// comes before the next poll.
+func pollInterval(mg resource.Managed, interval time.Duration) time.Duration {
+	cr, ok := mg.(*v1alpha1.ComputeSchedule)
+	if !ok || cr.Status.AtProvider.NextTransition == nil {
+		return interval
+	}
+
+	until := time.Until(cr.Status.AtProvider.NextTransition.Time) + transitionDelay
+	if until < transitionDelay {
+		return transitionDelay
+	}
+	if until < interval {
+		return until
+	}
+
+	return interval
+}
+
+type connector struct {
+	kube     client.Client
+	logger   logging.Logger
+	recorder event.Recorder
+}
+
+func (c *connector) Connect(_ context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	if _, ok := mg.(*v1alpha1.ComputeSchedule); !ok {
+		return nil, errors.New(errNotComputeSchedule)
+	}
+
+	return &external{kube: c.kube, logger: c.logger, recorder: c.recorder}, nil
+}
+
+// An external keeps the power state annotation of the Computes selected by a
+// schedule in line with it. The Compute controller then starts or stops their
+// instances like for any other change of their power state.
+type external struct {
+	kube     client.Client
+	logger   logging.Logger
+	recorder event.Recorder
+}
+
+// computes are the Computes a schedule applies to, those it no longer applies
+// to but still carry its annotations, and those it selects but another
+// schedule applies to.
+type computes struct {
+	selected []*v1alpha1.Compute
+	released []*v1alpha1.Compute
+	claimed  []*v1alpha1.Compute
+}
+
+func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	cr, ok := mg.(*v1alpha1.ComputeSchedule)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotComputeSchedule)
+	}
+
+	log := c.logger.WithValues("action", "observe", "resource", cr.Name)
+
+	if meta.WasDeleted(cr) {
+		cr.SetConditions(xpv1.Deleting())
+		annotated, err := c.annotatedComputes(ctx, cr)
+		if err != nil {
+			return managed.ExternalObservation{}, err
+		}
+		return managed.ExternalObservation{ResourceExists: len(annotated) > 0, ResourceUpToDate: true}, nil
+	}
+
+	desired, err := c.observeSchedule(cr)
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errParseSchedule)
+	}
+
+	cs, err := c.selectComputes(ctx, cr)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+
+	cr.Status.AtProvider.Computes = nil
+	upToDate := len(cs.released) == 0
+	for _, compute := range cs.selected {
+		cr.Status.AtProvider.Computes = append(cr.Status.AtProvider.Computes, compute.Name)
+		if !scheduled(compute, cr.Name, desired) {
+			upToDate = false
+		}
+	}
+	c.reportClaims(cr, cs.claimed)
+	cr.SetConditions(xpv1.Available())
+
+	log.Debug("observed schedule",
+		"powerState", desired,
+		"nextTransition", cr.Status.AtProvider.NextTransition,
+		"computes", cr.Status.AtProvider.Computes,
+		"released", len(cs.released),
+	)
+
+	return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: upToDate}, nil
+}
+
+// Create is never called, a schedule exists as long as its resource does.
+func (c *external) Create(_ context.Context, _ resource.Managed) (managed.ExternalCreation, error) {
+	return managed.ExternalCreation{}, nil
+}
+
+func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	cr, ok := mg.(*v1alpha1.ComputeSchedule)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotComputeSchedule)
+	}
+
+	log := c.logger.WithValues("action", "update", "resource", cr.Name)
+
+	desired, err := c.observeSchedule(cr)
+	if err != nil {
+		return managed.ExternalUpdate{}, errors.Wrap(err, errParseSchedule)
+	}
+
+	cs, err := c.selectComputes(ctx, cr)
+	if err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+
+	for _, compute := range cs.released {
+		log.Info("releasing compute", "compute", compute.Name)
+		if err := c.removeAnnotations(ctx, compute); err != nil {
+			return managed.ExternalUpdate{}, err
+		}
+	}
+
+	var changed []string
+	for _, compute := range cs.selected {
+		if scheduled(compute, cr.Name, desired) {
+			continue
+		}
+
+		previous := compute.GetAnnotations()[v1alpha1.AnnotationKeyPowerState]
+		patch := client.MergeFrom(compute.DeepCopy())
+		meta.AddAnnotations(compute, map[string]string{
+			v1alpha1.AnnotationKeyPowerState: string(desired),
+			v1alpha1.AnnotationKeySchedule:   cr.Name,
+		})
+		if err := c.kube.Patch(ctx, compute, patch); err != nil {
+			return managed.ExternalUpdate{}, errors.Wrapf(err, errPatchCompute, compute.Name)
+		}
+
+		if previous == string(desired) {
+			continue
+		}
+		log.Info("scheduled power state", "compute", compute.Name, "powerState", desired)
+		c.recorder.Event(compute, event.Normal(reasonPowerStateScheduled,
+			fmt.Sprintf("ComputeSchedule %s set the power state to %s", cr.Name, desired)))
+		changed = append(changed, compute.Name)
+	}
+
+	if len(changed) > 0 {
+		now := metav1.Now()
+		cr.Status.AtProvider.LastAction = action(desired)
+		cr.Status.AtProvider.LastActionTime = &now
+		c.recorder.Event(cr, event.Normal(reasonPowerStateScheduled,
+			fmt.Sprintf("%s Computes %s", action(desired), strings.Join(changed, ", "))))
+	}
+
+	return managed.ExternalUpdate{}, nil
+}
+
+// Delete hands the Computes back to their own power state.
+func (c *external) Delete(ctx context.Context, mg resource.Managed) error {
+	cr, ok := mg.(*v1alpha1.ComputeSchedule)
+	if !ok {
+		return errors.New(errNotComputeSchedule)
+	}
+
+	cr.SetConditions(xpv1.Deleting())
+
+	annotated, err := c.annotatedComputes(ctx, cr)
+	if err != nil {
+		return err
+	}
+
+	for _, compute := range annotated {
+		if err := c.removeAnnotations(ctx, compute); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// observeSchedule records the current and next power state of the schedule
+// in its status and returns the current one.
+func (c *external) observeSchedule(cr *v1alpha1.ComputeSchedule) (v1alpha1.PowerState, error) {
+	s, err := parseSchedule(cr.Spec.ForProvider)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	running := s.running(now)
+
+	cr.Status.AtProvider.PowerState = powerState(cr, running)
+	cr.Status.AtProvider.NextTransition = nil
+	cr.Status.AtProvider.NextPowerState = ""
+	if next := s.nextTransition(now); !next.IsZero() {
+		t := metav1.NewTime(next)
+		cr.Status.AtProvider.NextTransition = &t
+		cr.Status.AtProvider.NextPowerState = powerState(cr, !running)
+	}
+
+	return cr.Status.AtProvider.PowerState, nil
+}
+
+// selectComputes returns the Computes matched by the selector of the schedule
+// and those it released. A Compute already claimed by another schedule is left
+// to it.
+func (c *external) selectComputes(ctx context.Context, cr *v1alpha1.ComputeSchedule) (computes, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&cr.Spec.ForProvider.ComputeSelector)
+	if err != nil {
+		return computes{}, errors.Wrap(err, errComputeSelector)
+	}
+
+	list := &v1alpha1.ComputeList{}
+	if err := c.kube.List(ctx, list); err != nil {
+		return computes{}, errors.Wrap(err, errListComputes)
+	}
+
+	var cs computes
+	for i := range list.Items {
+		compute := &list.Items[i]
+		owner := compute.GetAnnotations()[v1alpha1.AnnotationKeySchedule]
+
+		switch {
+		case !selector.Matches(labels.Set(compute.GetLabels())):
+			if owner == cr.Name {
+				cs.released = append(cs.released, compute)
+			}
+		case owner != "" && owner != cr.Name:
+			cs.claimed = append(cs.claimed, compute)
+		default:
+			cs.selected = append(cs.selected, compute)
+		}
+	}
+
+	return cs, nil
+}
+
+// reportClaims records the selected Computes another schedule applies to. A
+// warning is recorded when a Compute is first found claimed, the condition is
+// only added once one is.
+func (c *external) reportClaims(cr *v1alpha1.ComputeSchedule, claimed []*v1alpha1.Compute) {
+	reported := make(map[string]bool, len(cr.Status.AtProvider.ClaimedComputes))
+	for _, name := range cr.Status.AtProvider.ClaimedComputes {
+		reported[name] = true
+	}
+
+	cr.Status.AtProvider.ClaimedComputes = nil
+	claims := make([]string, 0, len(claimed))
+	for _, compute := range claimed {
+		owner := compute.GetAnnotations()[v1alpha1.AnnotationKeySchedule]
+		cr.Status.AtProvider.ClaimedComputes = append(cr.Status.AtProvider.ClaimedComputes, compute.Name)
+		claims = append(claims, fmt.Sprintf("%s by %s", compute.Name, owner))
+
+		if !reported[compute.Name] {
+			c.recorder.Event(cr, event.Warning(reasonComputeClaimed,
+				errors.Errorf("Compute %s is already scheduled by ComputeSchedule %s", compute.Name, owner)))
+		}
+	}
+
+	if len(claimed) == 0 {
+		if cr.GetCondition(typeComputesClaimed).Status != corev1.ConditionUnknown {
+			cr.SetConditions(xpv1.Condition{
+				Type:               typeComputesClaimed,
+				Status:             corev1.ConditionFalse,
+				LastTransitionTime: metav1.Now(),
+				Reason:             reasonNoClaims,
+			})
+		}
+		return
+	}
+
+	cr.SetConditions(xpv1.Condition{
+		Type:               typeComputesClaimed,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             reasonClaimedElsewhere,
+		Message:            fmt.Sprintf("Computes already scheduled: %s", strings.Join(claims, ", ")),
+	})
+}
+
+// annotatedComputes returns the Computes carrying the annotations of the
+// schedule, whether or not it still selects them.
+func (c *external) annotatedComputes(ctx context.Context, cr *v1alpha1.ComputeSchedule) ([]*v1alpha1.Compute, error) {
+	list := &v1alpha1.ComputeList{}
+	if err := c.kube.List(ctx, list); err != nil {
+		return nil, errors.Wrap(err, errListComputes)
+	}
+
+	var annotated []*v1alpha1.Compute
+	for i := range list.Items {
+		if list.Items[i].GetAnnotations()[v1alpha1.AnnotationKeySchedule] == cr.Name {
+			annotated = append(annotated, &list.Items[i])
+		}
+	}
+
+	return annotated, nil
+}
+
+func (c *external) removeAnnotations(ctx context.Context, compute *v1alpha1.Compute) error {
+	patch := client.MergeFrom(compute.DeepCopy())
+	meta.RemoveAnnotations(compute, v1alpha1.AnnotationKeyPowerState, v1alpha1.AnnotationKeySchedule)
+	return errors.Wrapf(c.kube.Patch(ctx, compute, patch), errPatchCompute, compute.Name)
+}
+
+// scheduled reports whether the Compute carries the power state of the
+// schedule.
+func scheduled(compute *v1alpha1.Compute, schedule string, state v1alpha1.PowerState) bool {
+	a := compute.GetAnnotations()
+	return a[v1alpha1.AnnotationKeySchedule] == schedule && a[v1alpha1.AnnotationKeyPowerState] == string(state)
+}
+
+// powerState returns the power state of the Computes, running within the
+// windows and in the stop state of the schedule outside of them.
+func powerState(cr *v1alpha1.ComputeSchedule, running bool) v1alpha1.PowerState {
+	switch {
+	case running:
+		return v1alpha1.PowerStateRunning
+	case cr.Spec.ForProvider.StopState == "":
+		return v1alpha1.PowerStateStopped
+	default:
+		return cr.Spec.ForProvider.StopState
+	}
+}
+
+// action names the transition to the power state.
+func action(state v1alpha1.PowerState) string {
+	switch state {
+	case v1alpha1.PowerStateRunning:
+		return "Started"
+	case v1alpha1.PowerStateHibernated:
+		return "Hibernated"
+	default:
+		return "Stopped"
+	}
+}
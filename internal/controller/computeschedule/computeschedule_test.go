@@ -0,0 +1,222 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package computeschedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+)
+
+func TestSchedule(t *testing.T) {
+	officeHours := []v1alpha1.ScheduleWindow{{Start: "0 8 * * 1-5", Stop: "0 18 * * 1-5"}}
+
+	type want struct {
+		running bool
+		next    time.Time
+	}
+
+	cases := map[string]struct {
+		reason   string
+		timezone string
+		windows  []v1alpha1.ScheduleWindow
+		now      time.Time
+		want     want
+	}{
+		"WithinWindow": {
+			reason:  "A time between the start and the stop of a window should be running until the stop.",
+			windows: officeHours,
+			now:     time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC),
+			want:    want{running: true, next: time.Date(2024, 3, 5, 18, 0, 0, 0, time.UTC)},
+		},
+		"OnStart": {
+			reason:  "The start of a window should be running.",
+			windows: officeHours,
+			now:     time.Date(2024, 3, 5, 8, 0, 0, 0, time.UTC),
+			want:    want{running: true, next: time.Date(2024, 3, 5, 18, 0, 0, 0, time.UTC)},
+		},
+		"Overnight": {
+			reason:  "A time after the stop of a window should be stopped until the next start.",
+			windows: officeHours,
+			now:     time.Date(2024, 3, 5, 22, 0, 0, 0, time.UTC),
+			want:    want{running: false, next: time.Date(2024, 3, 6, 8, 0, 0, 0, time.UTC)},
+		},
+		"Weekend": {
+			reason:  "A window should stay stopped on the days it does not start.",
+			windows: officeHours,
+			now:     time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC),
+			want:    want{running: false, next: time.Date(2024, 3, 11, 8, 0, 0, 0, time.UTC)},
+		},
+		"Timezone": {
+			reason:   "Windows should be evaluated in the timezone of the schedule.",
+			timezone: "Europe/Berlin",
+			windows:  officeHours,
+			now:      time.Date(2024, 3, 5, 17, 30, 0, 0, time.UTC),
+			want:     want{running: false, next: time.Date(2024, 3, 6, 7, 0, 0, 0, time.UTC)},
+		},
+		"AdjacentWindows": {
+			reason: "A window starting when another stops should not produce a transition.",
+			windows: []v1alpha1.ScheduleWindow{
+				{Start: "0 8 * * *", Stop: "0 12 * * *"},
+				{Start: "0 12 * * *", Stop: "0 18 * * *"},
+			},
+			now:  time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC),
+			want: want{running: true, next: time.Date(2024, 3, 5, 18, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s, err := parseSchedule(v1alpha1.ComputeScheduleParameters{Windows: tc.windows, Timezone: tc.timezone})
+			if err != nil {
+				t.Fatalf("parseSchedule(...): %v", err)
+			}
+
+			got := want{running: s.running(tc.now), next: s.nextTransition(tc.now).UTC()}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nschedule: -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestPollInterval(t *testing.T) {
+	transitionIn := func(d time.Duration) *v1alpha1.ComputeSchedule {
+		next := metav1.NewTime(time.Now().Add(d))
+		return &v1alpha1.ComputeSchedule{Status: v1alpha1.ComputeScheduleStatus{AtProvider: v1alpha1.ComputeScheduleObservation{
+			NextTransition: &next,
+		}}}
+	}
+
+	cases := map[string]struct {
+		reason   string
+		cr       *v1alpha1.ComputeSchedule
+		interval time.Duration
+		want     time.Duration
+	}{
+		"NoTransition": {
+			reason:   "A schedule without a next transition should keep the configured poll interval.",
+			cr:       &v1alpha1.ComputeSchedule{},
+			interval: time.Minute,
+			want:     time.Minute,
+		},
+		"DistantTransition": {
+			reason:   "A transition after the next poll should keep the configured poll interval.",
+			cr:       transitionIn(time.Hour),
+			interval: time.Minute,
+			want:     time.Minute,
+		},
+		"MissedTransition": {
+			reason:   "A transition already behind should be acted on right away.",
+			cr:       transitionIn(-time.Minute),
+			interval: time.Minute,
+			want:     transitionDelay,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := pollInterval(tc.cr, tc.interval)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\npollInterval(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+type recorder struct {
+	events []event.Event
+}
+
+func (r *recorder) Event(_ runtime.Object, e event.Event) { r.events = append(r.events, e) }
+
+func (r *recorder) WithAnnotations(_ ...string) event.Recorder { return r }
+
+func TestReportClaims(t *testing.T) {
+	claimedBy := func(name, schedule string) *v1alpha1.Compute {
+		return &v1alpha1.Compute{ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Annotations: map[string]string{v1alpha1.AnnotationKeySchedule: schedule},
+		}}
+	}
+
+	type want struct {
+		claimed   []string
+		warnings  int
+		condition corev1.ConditionStatus
+	}
+
+	cases := map[string]struct {
+		reason   string
+		reported []string
+		claimed  []*v1alpha1.Compute
+		want     want
+	}{
+		"NoClaims": {
+			reason: "A schedule without conflicts should not be given the condition.",
+			want:   want{condition: corev1.ConditionUnknown},
+		},
+		"NewClaim": {
+			reason:  "A Compute first found claimed by another schedule should be warned about.",
+			claimed: []*v1alpha1.Compute{claimedBy("web", "nights")},
+			want:    want{claimed: []string{"web"}, warnings: 1, condition: corev1.ConditionTrue},
+		},
+		"KnownClaim": {
+			reason:   "A Compute already reported as claimed should not be warned about again.",
+			reported: []string{"web"},
+			claimed:  []*v1alpha1.Compute{claimedBy("web", "nights"), claimedBy("db", "nights")},
+			want:     want{claimed: []string{"web", "db"}, warnings: 1, condition: corev1.ConditionTrue},
+		},
+		"Released": {
+			reason:   "A conflict that is gone should clear the condition.",
+			reported: []string{"web"},
+			want:     want{condition: corev1.ConditionFalse},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cr := &v1alpha1.ComputeSchedule{ObjectMeta: metav1.ObjectMeta{Name: "office-hours"}}
+			cr.Status.AtProvider.ClaimedComputes = tc.reported
+			if len(tc.reported) > 0 {
+				cr.SetConditions(xpv1.Condition{Type: typeComputesClaimed, Status: corev1.ConditionTrue, Reason: reasonClaimedElsewhere})
+			}
+
+			r := &recorder{}
+			e := &external{recorder: r}
+			e.reportClaims(cr, tc.claimed)
+
+			got := want{
+				claimed:   cr.Status.AtProvider.ClaimedComputes,
+				warnings:  len(r.events),
+				condition: cr.GetCondition(typeComputesClaimed).Status,
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nreportClaims(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package computeschedule
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+)
+
+// maxTransitionSteps bounds the search for the next transition, windows that
+// overlap all the time never produce one.
+const maxTransitionSteps = 100
+
+type window struct {
+	start cron.Schedule
+	stop  cron.Schedule
+}
+
+// running reports whether t falls within the window, that is whether the
+// window stops before it starts again.
+func (w window) running(t time.Time) bool {
+	return w.stop.Next(t).Before(w.start.Next(t))
+}
+
+// A schedule tells when the selected Computes run.
+type schedule struct {
+	windows  []window
+	location *time.Location
+}
+
+func parseSchedule(p v1alpha1.ComputeScheduleParameters) (*schedule, error) {
+	tz := p.Timezone
+	if tz == "" {
+		tz = "UTC"
+	}
+	location, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", tz, err)
+	}
+
+	s := &schedule{location: location}
+	for i, w := range p.Windows {
+		start, err := cron.ParseStandard(w.Start)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start of window %d: %w", i, err)
+		}
+		stop, err := cron.ParseStandard(w.Stop)
+		if err != nil {
+			return nil, fmt.Errorf("invalid stop of window %d: %w", i, err)
+		}
+		s.windows = append(s.windows, window{start: start, stop: stop})
+	}
+
+	return s, nil
+}
+
+// running reports whether t falls within any of the windows.
+func (s *schedule) running(t time.Time) bool {
+	t = t.In(s.location)
+	for _, w := range s.windows {
+		if w.running(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextTransition returns when the schedule next switches the Computes between
+// running and stopped, or the zero time when it never does.
+func (s *schedule) nextTransition(now time.Time) time.Time {
+	current := s.running(now)
+
+	t := now.In(s.location)
+	for i := 0; i < maxTransitionSteps; i++ {
+		next := time.Time{}
+		for _, w := range s.windows {
+			for _, edge := range []time.Time{w.start.Next(t), w.stop.Next(t)} {
+				if !edge.IsZero() && (next.IsZero() || edge.Before(next)) {
+					next = edge
+				}
+			}
+		}
+		if next.IsZero() {
+			break
+		}
+		if s.running(next) != current {
+			return next
+		}
+		t = next
+	}
+
+	return time.Time{}
+}
@@ -21,6 +21,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	"github.com/crossplane/provider-customcomputeprovider/internal/controller/compute"
+	"github.com/crossplane/provider-customcomputeprovider/internal/controller/computeschedule"
 	"github.com/crossplane/provider-customcomputeprovider/internal/controller/config"
 )
 
@@ -30,6 +31,7 @@ func Setup(mgr ctrl.Manager, o controller.Options) error {
 	for _, setup := range []func(ctrl.Manager, controller.Options) error{
 		config.Setup,
 		compute.Setup,
+		computeschedule.Setup,
 	} {
 		if err := setup(mgr, o); err != nil {
 			return err
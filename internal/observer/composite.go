@@ -2,6 +2,7 @@ package validation
 
 import (
 	"context"
+	"errors"
 
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
@@ -18,6 +19,10 @@ type CompositeValidator struct {
 type ValidationResult struct {
 	HasUpdates      bool
 	UpdatesRequired map[string]bool
+
+	// Err joins the errors that kept validators from comparing the
+	// instance.
+	Err error
 }
 
 func NewCompositeValidator(logger logging.Logger, client *provider.EC2Client) *CompositeValidator {
@@ -30,18 +35,25 @@ func NewCompositeValidator(logger logging.Logger, client *provider.EC2Client) *C
 			&InstanceTypeValidator{},
 			&TagValidator{},
 			&SecurityGroupValidator{},
+			&IAMInstanceProfileValidator{},
+			&MetadataOptionsValidator{},
+			&PowerStateValidator{},
 			&VolumeValidator{},
+			&UserDataValidator{},
 		},
 	}
 }
 
-func (cv *CompositeValidator) ValidateAll(ctx context.Context, currentInstance *types.Instance, desiredInstance *v1alpha1.InstanceConfig) ValidationResult {
+func (cv *CompositeValidator) ValidateAll(ctx context.Context, currentInstance *types.Instance, desiredInstance *v1alpha1.InstanceConfig, status *v1alpha1.ComputeObservation) ValidationResult {
 	result := ValidationResult{UpdatesRequired: make(map[string]bool)}
+	var errs []error
 	validationContext := ValidationContext{
 		Context:   ctx,
 		Current:   currentInstance,
 		Desired:   desiredInstance,
+		Status:    status,
 		EC2Client: cv.client,
+		errs:      &errs,
 	}
 
 	for _, v := range cv.validators {
@@ -52,5 +64,6 @@ func (cv *CompositeValidator) ValidateAll(ctx context.Context, currentInstance *
 		}
 		cv.logger.Info("validation result", "type", v.GetValidationType(), "needs_update", needsUpdate)
 	}
+	result.Err = errors.Join(errs...)
 	return result
 }
@@ -0,0 +1,18 @@
+package validation
+
+import (
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+	o "github.com/crossplane/provider-customcomputeprovider/internal/types"
+)
+
+// IAMInstanceProfileValidator compares the instance profile attached to the
+// instance with the desired one. An empty profile means none is attached.
+type IAMInstanceProfileValidator struct{}
+
+func (v *IAMInstanceProfileValidator) NeedsUpdate(ctx ValidationContext) bool {
+	return !provider.InstanceProfileMatches(ctx.Current.IamInstanceProfile, ctx.Desired.IAMInstanceProfile)
+}
+
+func (*IAMInstanceProfileValidator) GetValidationType() string {
+	return o.IAM_PROFILE.String()
+}
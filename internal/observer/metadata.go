@@ -0,0 +1,18 @@
+package validation
+
+import (
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+	o "github.com/crossplane/provider-customcomputeprovider/internal/types"
+)
+
+// MetadataOptionsValidator compares the metadata options of the instance with
+// the desired ones, they are only managed when the Compute configures them.
+type MetadataOptionsValidator struct{}
+
+func (v *MetadataOptionsValidator) NeedsUpdate(ctx ValidationContext) bool {
+	return !provider.MetadataOptionsMatch(ctx.Current.MetadataOptions, ctx.Desired.MetadataOptions)
+}
+
+func (*MetadataOptionsValidator) GetValidationType() string {
+	return o.METADATA.String()
+}
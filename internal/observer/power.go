@@ -0,0 +1,18 @@
+package validation
+
+import (
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+	o "github.com/crossplane/provider-customcomputeprovider/internal/types"
+)
+
+// PowerStateValidator compares the state of the instance with the desired
+// power state.
+type PowerStateValidator struct{}
+
+func (v *PowerStateValidator) NeedsUpdate(ctx ValidationContext) bool {
+	return !provider.InPowerState(ctx.Current, provider.DesiredPowerState(ctx.Desired))
+}
+
+func (*PowerStateValidator) GetValidationType() string {
+	return o.POWER_STATE.String()
+}
@@ -2,6 +2,7 @@ package validation
 
 import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
 	o "github.com/crossplane/provider-customcomputeprovider/internal/types"
 	"github.com/crossplane/provider-customcomputeprovider/pkg/generic"
 )
@@ -16,7 +17,7 @@ func (v *TagValidator) compareTagMaps(current, desired map[string]string) bool {
 	}
 
 	for k := range current {
-		if k == "Name" {
+		if k == "Name" || k == provider.OwnerTagKey {
 			continue
 		}
 		if _, exists := desired[k]; !exists {
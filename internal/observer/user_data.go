@@ -0,0 +1,27 @@
+package validation
+
+import (
+	o "github.com/crossplane/provider-customcomputeprovider/internal/types"
+)
+
+// UserDataValidator compares the user data of the instance with the desired
+// one. User data is only managed when the Compute configures it.
+type UserDataValidator struct{}
+
+func (v *UserDataValidator) NeedsUpdate(ctx ValidationContext) bool {
+	if ctx.Desired.UserData == "" {
+		return false
+	}
+
+	current, err := ctx.EC2Client.GetUserData(ctx.Context, *ctx.Current.InstanceId)
+	if err != nil {
+		ctx.ReportError(err)
+		return false
+	}
+
+	return current != ctx.Desired.UserData
+}
+
+func (*UserDataValidator) GetValidationType() string {
+	return o.USER_DATA.String()
+}
@@ -0,0 +1,82 @@
+package validation
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider/fake"
+)
+
+func TestUserDataValidator(t *testing.T) {
+	errBoom := errors.New("boom")
+	userData := func(data string) fake.Output {
+		return fake.Answer(&ec2.DescribeInstanceAttributeOutput{
+			UserData: &types.AttributeValue{Value: aws.String(base64.StdEncoding.EncodeToString([]byte(data)))},
+		})
+	}
+
+	type want struct {
+		needsUpdate bool
+		errs        []error
+		calls       []string
+	}
+
+	cases := map[string]struct {
+		reason   string
+		desired  string
+		userData fake.Output
+		want     want
+	}{
+		"Unmanaged": {
+			reason: "User data should not be compared when the Compute does not configure it.",
+		},
+		"UpToDate": {
+			reason:   "The same user data should need no update.",
+			desired:  "#!/bin/sh",
+			userData: userData("#!/bin/sh"),
+			want:     want{calls: []string{"DescribeInstanceAttribute"}},
+		},
+		"Drifted": {
+			reason:   "Different user data should need an update.",
+			desired:  "#!/bin/sh",
+			userData: userData("#!/bin/bash"),
+			want:     want{needsUpdate: true, calls: []string{"DescribeInstanceAttribute"}},
+		},
+		"Error": {
+			reason:   "A failure to read the user data should be reported rather than taken for an instance up to date.",
+			desired:  "#!/bin/sh",
+			userData: fake.Fail(errBoom),
+			want:     want{errs: []error{errBoom}, calls: []string{"DescribeInstanceAttribute"}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fakeEC2 := &fake.EC2{Outputs: map[string]fake.Output{"DescribeInstanceAttribute": tc.userData}}
+			var errs []error
+
+			needsUpdate := (&UserDataValidator{}).NeedsUpdate(ValidationContext{
+				Context:   context.Background(),
+				Current:   &types.Instance{InstanceId: aws.String("i-1")},
+				Desired:   &v1alpha1.InstanceConfig{UserData: tc.desired},
+				EC2Client: &provider.EC2Client{Client: fakeEC2.Client()},
+				errs:      &errs,
+			})
+
+			got := want{needsUpdate: needsUpdate, errs: errs, calls: fakeEC2.Calls}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nNeedsUpdate(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -17,5 +17,17 @@ type ValidationContext struct {
 	Context   context.Context
 	Current   *types.Instance
 	Desired   *v1alpha1.InstanceConfig
+	Status    *v1alpha1.ComputeObservation
 	EC2Client *provider.EC2Client
+
+	errs *[]error
+}
+
+// ReportError records an error that kept a validator from comparing the
+// instance with the desired configuration. The validation result carries it,
+// so a failed comparison is not mistaken for an instance that is up to date.
+func (ctx ValidationContext) ReportError(err error) {
+	if ctx.errs != nil {
+		*ctx.errs = append(*ctx.errs, err)
+	}
 }
@@ -24,6 +24,7 @@ func (v *VolumeValidator) NeedsUpdate(ctx ValidationContext) bool {
 	analyzer := shared.NewCommandAnalyzer()
 	state := analyzer.BuildVolumeState(output, ctx.Current)
 	state.Desired = ctx.Desired.Storage
+	state.Tracked = ctx.Status.StorageVolumes
 	commands := analyzer.AnalyzeChanges(state)
 
 	return len(commands) > 0
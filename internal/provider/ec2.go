@@ -2,25 +2,57 @@ package provider
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"log/slog"
+	"strings"
 
 	"github.com/pkg/errors"
+	k8stypes "k8s.io/apimachinery/pkg/types"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
 )
 
+const (
+	instanceIDPrefix = "i-"
+
+	// OwnerTagKey tags every instance launched by the provider with the UID of
+	// the Compute that owns it.
+	OwnerTagKey = "customcomputeprovider.crossplane.io/compute-uid"
+)
+
 var errInstanceNotFound = errors.New("error instance not found")
 
+// InstanceID returns the ID of the instance backing the Compute. The
+// external-name annotation is authoritative; the status is only consulted for
+// resources whose annotation does not hold an instance ID yet.
+func InstanceID(cr *v1alpha1.Compute) string {
+	if externalName := meta.GetExternalName(cr); strings.HasPrefix(externalName, instanceIDPrefix) {
+		return externalName
+	}
+
+	return cr.Status.AtProvider.InstanceID
+}
+
 func (e *EC2Client) GetInstanceByID(ctx context.Context, instanceID string) (*types.Instance, error) {
 	instance, err := e.Client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
 		InstanceIds: []string{instanceID},
 	})
 
 	if err != nil {
+		if isInstanceNotFound(err) {
+			return nil, errInstanceNotFound
+		}
+		if isInstanceIDMalformed(err) {
+			return nil, fmt.Errorf("instance ID %s is malformed, check the %s annotation: %w", instanceID, meta.AnnotationKeyExternalName, err)
+		}
 		return nil, fmt.Errorf("failed to describe ec2 instance %s: %w", instanceID, err)
 	}
 
@@ -31,67 +63,243 @@ func (e *EC2Client) GetInstanceByID(ctx context.Context, instanceID string) (*ty
 	return &instance.Reservations[0].Instances[0], nil
 }
 
-func (e *EC2Client) Observe(ctx context.Context, cr *v1alpha1.Compute, resourceName string) (bool, *types.Instance, error) {
+// GetUserData returns the decoded user data of the instance.
+func (e *EC2Client) GetUserData(ctx context.Context, instanceID string) (string, error) {
+	output, err := e.Client.DescribeInstanceAttribute(ctx, &ec2.DescribeInstanceAttributeInput{
+		InstanceId: &instanceID,
+		Attribute:  types.InstanceAttributeNameUserData,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe user data of ec2 instance %s: %w", instanceID, err)
+	}
+
+	if output.UserData == nil || output.UserData.Value == nil {
+		return "", nil
+	}
+
+	userData, err := base64.StdEncoding.DecodeString(*output.UserData.Value)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode user data of ec2 instance %s: %w", instanceID, err)
+	}
+
+	return string(userData), nil
+}
+
+// GetInstanceStatus returns the status checks of the instance. It returns nil
+// when EC2 reports none, e.g. while the instance is not running.
+func (e *EC2Client) GetInstanceStatus(ctx context.Context, instanceID string) (*types.InstanceStatus, error) {
+	output, err := e.Client.DescribeInstanceStatus(ctx, &ec2.DescribeInstanceStatusInput{
+		InstanceIds: []string{instanceID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe status of ec2 instance %s: %w", instanceID, err)
+	}
+
+	if len(output.InstanceStatuses) == 0 {
+		return nil, nil
+	}
+
+	return &output.InstanceStatuses[0], nil
+}
+
+func isInstanceNotFound(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidInstanceID.NotFound"
+}
+
+// isInstanceIDMalformed reports an ID that cannot name any instance, e.g. a
+// mistyped external name. It is not taken for a vanished instance, which
+// would launch a new one in place of the instance being adopted.
+func isInstanceIDMalformed(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidInstanceID.Malformed"
+}
+
+// ClientToken derives the idempotency token sent with RunInstances. The seed
+// tells apart the successive instances of a single Compute, e.g. the ID of the
+// instance being replaced, and is empty for the first one.
+func ClientToken(uid k8stypes.UID, seed string) string {
+	sum := sha256.Sum256([]byte(string(uid) + "/" + seed))
+	return hex.EncodeToString(sum[:])
+}
+
+// FindOwnedInstance looks for a live instance launched on behalf of the
+// Compute, either through its idempotency token or its owner tag. It covers
+// creations whose instance ID was never recorded.
+func (e *EC2Client) FindOwnedInstance(ctx context.Context, cr *v1alpha1.Compute) (*types.Instance, error) {
+	filters := [][]types.Filter{
+		{{Name: aws.String("client-token"), Values: []string{ClientToken(cr.GetUID(), "")}}},
+		{{Name: aws.String("tag:" + OwnerTagKey), Values: []string{string(cr.GetUID())}}},
+	}
+
+	for _, filter := range filters {
+		output, err := e.Client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{Filters: filter})
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up instances owned by %s: %w", cr.GetName(), err)
+		}
+
+		var found *types.Instance
+		for _, reservation := range output.Reservations {
+			for i := range reservation.Instances {
+				instance := &reservation.Instances[i]
+				if !isLive(instance) {
+					continue
+				}
+				if found == nil || aws.ToTime(instance.LaunchTime).After(aws.ToTime(found.LaunchTime)) {
+					found = instance
+				}
+			}
+		}
+
+		if found != nil {
+			return found, nil
+		}
+	}
+
+	return nil, errInstanceNotFound
+}
+
+func isLive(instance *types.Instance) bool {
+	switch instance.State.Name {
+	case types.InstanceStateNameShuttingDown, types.InstanceStateNameTerminated:
+		return false
+	}
+	return true
+}
+
+// Observe looks up the instance of the Compute. An instance that is shutting
+// down or terminated does not exist anymore, it is reported as such alongside
+// the instance itself so callers can tell it apart from one never launched.
+// An instance EC2 no longer describes is reported by IsVanished.
+func (e *EC2Client) Observe(ctx context.Context, cr *v1alpha1.Compute) (bool, *types.Instance, error) {
 	exists := true
 
-	instanceID := cr.Status.AtProvider.InstanceID
+	instanceID := InstanceID(cr)
+	if instanceID == "" {
+		instance, err := e.FindOwnedInstance(ctx, cr)
+		if err != nil {
+			if errors.Is(err, errInstanceNotFound) {
+				return !exists, nil, nil
+			}
+			return !exists, nil, err
+		}
+		return exists, instance, nil
+	}
 
+	// EC2 stops describing a terminated instance about an hour after its
+	// termination, an instance recorded in the external name that cannot be
+	// found anymore is reported as terminated rather than never launched.
 	instance, err := e.GetInstanceByID(ctx, instanceID)
 	if err != nil {
 		if errors.Is(err, errInstanceNotFound) {
-			return !exists, nil, nil
+			return !exists, vanishedInstance(instanceID), nil
 		}
 
-		return !false, nil, err
+		return true, nil, err
+	}
+
+	if !isLive(instance) {
+		return !exists, instance, nil
 	}
 
 	return exists, instance, nil
 }
 
-func (e *EC2Client) DeleteInstanceByID(ctx context.Context, instanceID string) error {
-	output, err := e.Client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
-		InstanceIds: []string{instanceID},
-	})
+// vanishedInstance stands for a terminated instance EC2 no longer describes.
+// It only carries its ID and state.
+func vanishedInstance(instanceID string) *types.Instance {
+	return &types.Instance{
+		InstanceId: &instanceID,
+		State:      &types.InstanceState{Name: types.InstanceStateNameTerminated},
+	}
+}
+
+// IsVanished reports whether the instance is one EC2 no longer describes, as
+// returned by Observe. EC2 reports a launch time for every instance it knows.
+func IsVanished(instance *types.Instance) bool {
+	return instance.LaunchTime == nil && instance.State != nil && instance.State.Name == types.InstanceStateNameTerminated
+}
 
+// DeleteInstanceByID requests the termination of the instance. It is
+// idempotent: an instance already gone, or on its way out, is left alone.
+func (e *EC2Client) DeleteInstanceByID(ctx context.Context, instanceID string) error {
+	instance, err := e.GetInstanceByID(ctx, instanceID)
 	if err != nil {
+		if errors.Is(err, errInstanceNotFound) {
+			slog.Info("instance not found for deletion, assuming it is gone", "instanceID", instanceID)
+			return nil
+		}
+
 		slog.Error("failed to describe ec2 instance", "err", err)
-		return fmt.Errorf("failed to describe ec2 instance: %w", err)
+		return err
 	}
 
-	if len(output.Reservations) == 0 {
-		slog.Info("instance not found for deletion", "err", err)
-		return fmt.Errorf("instance not found for deletion: %w", err)
+	if !isLive(instance) {
+		return nil
 	}
 
 	_, err = e.Client.TerminateInstances(ctx, &ec2.TerminateInstancesInput{
-		InstanceIds: []string{*output.Reservations[0].Instances[0].InstanceId},
+		InstanceIds: []string{instanceID},
 	})
+	if err != nil && !isInstanceNotFound(err) {
+		return fmt.Errorf("failed to terminate ec2 instance %s: %w", instanceID, err)
+	}
 
-	return err
+	return nil
 }
 
-func (e *EC2Client) CreateInstance(ctx context.Context, resource v1alpha1.InstanceConfig) (*ec2.RunInstancesOutput, error) {
+// CreateOption customizes the RunInstances request issued by CreateInstance.
+type CreateOption func(*ec2.RunInstancesInput)
+
+// WithClientToken makes the launch idempotent, retrying it with the same
+// token returns the instance launched by the first attempt.
+func WithClientToken(token string) CreateOption {
+	return func(in *ec2.RunInstancesInput) {
+		in.ClientToken = &token
+	}
+}
+
+// WithOwner tags the instance with the UID of the Compute that owns it.
+func WithOwner(uid k8stypes.UID) CreateOption {
+	return func(in *ec2.RunInstancesInput) {
+		for i := range in.TagSpecifications {
+			if in.TagSpecifications[i].ResourceType == types.ResourceTypeInstance {
+				in.TagSpecifications[i].Tags = append(in.TagSpecifications[i].Tags, types.Tag{
+					Key:   aws.String(OwnerTagKey),
+					Value: aws.String(string(uid)),
+				})
+			}
+		}
+	}
+}
+
+func (e *EC2Client) CreateInstance(ctx context.Context, resource v1alpha1.InstanceConfig, opts ...CreateOption) (*ec2.RunInstancesOutput, error) {
+	if resource.InstanceTags == nil {
+		resource.InstanceTags = make(map[string]string)
+	}
 	if _, found := resource.InstanceTags["Name"]; !found {
 		resource.InstanceTags["Name"] = resource.InstanceName
 	}
 
 	var computeInstanceTags []types.Tag
 	for key, value := range resource.InstanceTags {
+		if key == OwnerTagKey {
+			continue
+		}
 		computeInstanceTags = append(computeInstanceTags, types.Tag{Key: &key, Value: &value})
 	}
 
-	blockDeviceMapping := make([]types.BlockDeviceMapping, len(resource.Storage))
-
-	for i, storage := range resource.Storage {
-		blockDeviceMapping[i] = types.BlockDeviceMapping{
-			DeviceName: &storage.DeviceName,
-			Ebs: &types.EbsBlockDevice{
-				DeleteOnTermination: aws.Bool(true),
-				Encrypted:           aws.Bool(true),
-				VolumeType:          types.VolumeType(storage.InstanceDisk),
-				VolumeSize:          &storage.DiskSize,
-			},
+	// Pinned volumes already exist, they are attached once the instance is
+	// running.
+	var blockDeviceMapping []types.BlockDeviceMapping
+	for _, storage := range resource.Storage {
+		if storage.VolumeID != "" {
+			continue
 		}
+		blockDeviceMapping = append(blockDeviceMapping, types.BlockDeviceMapping{
+			DeviceName: aws.String(storage.DeviceName),
+			Ebs:        EBSBlockDevice(storage),
+		})
 	}
 
 	params := &ec2.RunInstancesInput{
@@ -103,6 +311,9 @@ func (e *EC2Client) CreateInstance(ctx context.Context, resource v1alpha1.Instan
 		MaxCount:            aws.Int32(1),
 		BlockDeviceMappings: blockDeviceMapping,
 
+		InstanceMarketOptions: instanceMarketOptions(resource.MarketOptions),
+		MetadataOptions:       MetadataOptionsRequest(resource.MetadataOptions),
+
 		TagSpecifications: []types.TagSpecification{
 			{
 				ResourceType: types.ResourceTypeInstance,
@@ -111,5 +322,25 @@ func (e *EC2Client) CreateInstance(ctx context.Context, resource v1alpha1.Instan
 		},
 	}
 
+	if resource.HibernationEnabled {
+		params.HibernationOptions = &types.HibernationOptionsRequest{Configured: aws.Bool(true)}
+	}
+
+	if resource.IAMInstanceProfile != "" {
+		params.IamInstanceProfile = InstanceProfileSpecification(resource.IAMInstanceProfile)
+	}
+
+	if resource.KeyName != "" {
+		params.KeyName = &resource.KeyName
+	}
+
+	if resource.UserData != "" {
+		params.UserData = aws.String(base64.StdEncoding.EncodeToString([]byte(resource.UserData)))
+	}
+
+	for _, opt := range opts {
+		opt(params)
+	}
+
 	return e.Client.RunInstances(ctx, params)
 }
@@ -0,0 +1,312 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider/fake"
+)
+
+// compute returns a Compute with the external name and the instance ID
+// recorded in its status.
+func compute(externalName, statusID string) *v1alpha1.Compute {
+	cr := &v1alpha1.Compute{}
+	cr.SetUID("compute-uid")
+	if externalName != "" {
+		meta.SetExternalName(cr, externalName)
+	}
+	cr.Status.AtProvider.InstanceID = statusID
+	return cr
+}
+
+func TestInstanceID(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		cr     *v1alpha1.Compute
+		want   string
+	}{
+		"ExternalName": {
+			reason: "The external name should win over the status.",
+			cr:     compute("i-imported", "i-status"),
+			want:   "i-imported",
+		},
+		"ExternalNameNotAnInstance": {
+			reason: "An external name that is not an instance ID, e.g. the default resource name, should fall back to the status.",
+			cr:     compute("web", "i-status"),
+			want:   "i-status",
+		},
+		"NoExternalName": {
+			reason: "The status should be used when the external name is not set.",
+			cr:     compute("", "i-status"),
+			want:   "i-status",
+		},
+		"None": {
+			reason: "A Compute never launched should have no instance ID.",
+			cr:     compute("web", ""),
+			want:   "",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, InstanceID(tc.cr)); diff != "" {
+				t.Errorf("\n%s\nInstanceID(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestObserveByID(t *testing.T) {
+	type want struct {
+		exists   bool
+		instance *types.Instance
+		err      bool
+	}
+
+	running := fake.Instance("i-imported", types.InstanceStateNameRunning)
+
+	cases := map[string]struct {
+		reason   string
+		describe fake.Output
+		want     want
+	}{
+		"Found": {
+			reason:   "A live instance should exist.",
+			describe: fake.DescribeInstances(running),
+			want:     want{exists: true, instance: &running},
+		},
+		"NotFound": {
+			reason:   "An instance EC2 no longer knows should be reported as vanished.",
+			describe: fake.Fail(&smithy.GenericAPIError{Code: "InvalidInstanceID.NotFound"}),
+			want:     want{instance: vanishedInstance("i-imported")},
+		},
+		"Malformed": {
+			reason:   "A malformed instance ID should fail rather than be taken for a vanished instance.",
+			describe: fake.Fail(&smithy.GenericAPIError{Code: "InvalidInstanceID.Malformed"}),
+			want:     want{exists: true, err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fakeEC2 := &fake.EC2{Outputs: map[string]fake.Output{"DescribeInstances": tc.describe}}
+			c := &EC2Client{Client: fakeEC2.Client()}
+
+			exists, instance, err := c.Observe(context.Background(), compute("i-imported", ""))
+
+			got := want{exists: exists, instance: instance, err: err != nil}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), cmp.Comparer(sameInstance)); diff != "" {
+				t.Errorf("\n%s\nObserve(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// sameInstance compares instances by ID and state.
+func sameInstance(a, b types.Instance) bool {
+	return aws.ToString(a.InstanceId) == aws.ToString(b.InstanceId) && a.State.Name == b.State.Name
+}
+
+func TestClientToken(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		a, b   string
+		same   bool
+	}{
+		"Stable": {
+			reason: "The same owner and seed should always give the same token.",
+			a:      ClientToken("compute-uid", "i-1/0"),
+			b:      ClientToken("compute-uid", "i-1/0"),
+			same:   true,
+		},
+		"Seed": {
+			reason: "A different seed should give a different token.",
+			a:      ClientToken("compute-uid", "i-1/0"),
+			b:      ClientToken("compute-uid", "i-1/1"),
+		},
+		"Owner": {
+			reason: "A different owner should give a different token.",
+			a:      ClientToken("compute-uid", ""),
+			b:      ClientToken("other-uid", ""),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.same, tc.a == tc.b); diff != "" {
+				t.Errorf("\n%s\nClientToken(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// describeByFilter describes the instances listed under the name of the
+// filter of the call.
+func describeByFilter(instances map[string][]types.Instance) fake.Output {
+	return func(input interface{}) (interface{}, error) {
+		output := &ec2.DescribeInstancesOutput{}
+		for _, filter := range input.(*ec2.DescribeInstancesInput).Filters {
+			if found := instances[aws.ToString(filter.Name)]; len(found) > 0 {
+				output.Reservations = append(output.Reservations, types.Reservation{Instances: found})
+			}
+		}
+		return output, nil
+	}
+}
+
+// launchedAt returns an instance in the given state launched at the time.
+func launchedAt(id string, state types.InstanceStateName, at time.Time) types.Instance {
+	instance := fake.Instance(id, state)
+	instance.LaunchTime = &at
+	return instance
+}
+
+func TestFindOwnedInstance(t *testing.T) {
+	type want struct {
+		instance *types.Instance
+		calls    int
+		err      error
+	}
+
+	earlier := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	later := earlier.Add(time.Hour)
+
+	byToken := launchedAt("i-token", types.InstanceStateNameRunning, earlier)
+	byTag := launchedAt("i-tag", types.InstanceStateNameRunning, earlier)
+	newer := launchedAt("i-newer", types.InstanceStateNamePending, later)
+	terminated := launchedAt("i-terminated", types.InstanceStateNameTerminated, later)
+	shuttingDown := launchedAt("i-shutting-down", types.InstanceStateNameShuttingDown, later)
+
+	cases := map[string]struct {
+		reason    string
+		instances map[string][]types.Instance
+		want      want
+	}{
+		"ClientToken": {
+			reason:    "An instance found through the launch token should be returned without looking at the owner tag.",
+			instances: map[string][]types.Instance{"client-token": {byToken}, "tag:" + OwnerTagKey: {byTag}},
+			want:      want{instance: &byToken, calls: 1},
+		},
+		"OwnerTag": {
+			reason:    "The owner tag should be looked up when no instance carries the launch token.",
+			instances: map[string][]types.Instance{"tag:" + OwnerTagKey: {byTag}},
+			want:      want{instance: &byTag, calls: 2},
+		},
+		"TerminatedIgnored": {
+			reason: "Terminated or terminating instances should be ignored, falling back to the owner tag.",
+			instances: map[string][]types.Instance{
+				"client-token":       {terminated, shuttingDown},
+				"tag:" + OwnerTagKey: {byTag},
+			},
+			want: want{instance: &byTag, calls: 2},
+		},
+		"Latest": {
+			reason:    "The latest launched instance should win when several are live.",
+			instances: map[string][]types.Instance{"tag:" + OwnerTagKey: {byTag, newer, terminated}},
+			want:      want{instance: &newer, calls: 2},
+		},
+		"NotFound": {
+			reason:    "No live owned instance should be reported as not found.",
+			instances: map[string][]types.Instance{"client-token": {terminated}},
+			want:      want{calls: 2, err: errInstanceNotFound},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fakeEC2 := &fake.EC2{Outputs: map[string]fake.Output{"DescribeInstances": describeByFilter(tc.instances)}}
+			c := &EC2Client{Client: fakeEC2.Client()}
+
+			instance, err := c.FindOwnedInstance(context.Background(), compute("", ""))
+
+			got := want{instance: instance, calls: len(fakeEC2.Calls), err: err}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), cmp.Comparer(sameInstance), cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nFindOwnedInstance(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestDeleteInstanceByID(t *testing.T) {
+	type want struct {
+		calls []string
+		err   bool
+	}
+
+	terminate := fake.Answer(&ec2.TerminateInstancesOutput{})
+
+	cases := map[string]struct {
+		reason  string
+		outputs map[string]fake.Output
+		want    want
+	}{
+		"Running": {
+			reason: "A live instance should be terminated.",
+			outputs: map[string]fake.Output{
+				"DescribeInstances":  fake.DescribeInstances(fake.Instance("i-1", types.InstanceStateNameRunning)),
+				"TerminateInstances": terminate,
+			},
+			want: want{calls: []string{"DescribeInstances", "TerminateInstances"}},
+		},
+		"AlreadyTerminated": {
+			reason: "An instance already terminated should not be terminated again.",
+			outputs: map[string]fake.Output{
+				"DescribeInstances": fake.DescribeInstances(fake.Instance("i-1", types.InstanceStateNameTerminated)),
+			},
+			want: want{calls: []string{"DescribeInstances"}},
+		},
+		"ShuttingDown": {
+			reason: "An instance on its way out should be left alone.",
+			outputs: map[string]fake.Output{
+				"DescribeInstances": fake.DescribeInstances(fake.Instance("i-1", types.InstanceStateNameShuttingDown)),
+			},
+			want: want{calls: []string{"DescribeInstances"}},
+		},
+		"NotFound": {
+			reason: "An instance EC2 no longer knows should be taken as deleted.",
+			outputs: map[string]fake.Output{
+				"DescribeInstances": fake.Fail(&smithy.GenericAPIError{Code: "InvalidInstanceID.NotFound"}),
+			},
+			want: want{calls: []string{"DescribeInstances"}},
+		},
+		"TerminateNotFound": {
+			reason: "An instance gone between the description and the termination should be taken as deleted.",
+			outputs: map[string]fake.Output{
+				"DescribeInstances":  fake.DescribeInstances(fake.Instance("i-1", types.InstanceStateNameRunning)),
+				"TerminateInstances": fake.Fail(&smithy.GenericAPIError{Code: "InvalidInstanceID.NotFound"}),
+			},
+			want: want{calls: []string{"DescribeInstances", "TerminateInstances"}},
+		},
+		"DescribeError": {
+			reason: "A failed description should be returned.",
+			outputs: map[string]fake.Output{
+				"DescribeInstances": fake.Fail(&smithy.GenericAPIError{Code: "RequestLimitExceeded"}),
+			},
+			want: want{calls: []string{"DescribeInstances"}, err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fakeEC2 := &fake.EC2{Outputs: tc.outputs}
+			c := &EC2Client{Client: fakeEC2.Client()}
+
+			err := c.DeleteInstanceByID(context.Background(), "i-1")
+
+			got := want{calls: fakeEC2.Calls, err: err != nil}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nDeleteInstanceByID(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
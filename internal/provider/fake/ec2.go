@@ -0,0 +1,88 @@
+// Package fake provides an EC2 client for tests that answers calls without
+// reaching AWS.
+package fake
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// An Output answers a call given its input.
+type Output func(input interface{}) (interface{}, error)
+
+// EC2 answers the EC2 calls of a test by operation name, and records the
+// calls in the order they are made. A call without an output fails.
+type EC2 struct {
+	Outputs map[string]Output
+	Calls   []string
+}
+
+// Client returns an EC2 client answered by the fake.
+func (f *EC2) Client() *ec2.Client {
+	return ec2.New(ec2.Options{
+		Region:     "us-east-1",
+		APIOptions: []func(*middleware.Stack) error{f.register},
+	})
+}
+
+// register answers the call at the end of the initialize step, once the input
+// is validated and before any request is signed or sent.
+func (f *EC2) register(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("FakeEC2",
+		func(ctx context.Context, in middleware.InitializeInput, _ middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			operation := awsmiddleware.GetOperationName(ctx)
+			f.Calls = append(f.Calls, operation)
+
+			output, ok := f.Outputs[operation]
+			if !ok {
+				return middleware.InitializeOutput{}, middleware.Metadata{}, fmt.Errorf("unexpected call to %s", operation)
+			}
+
+			result, err := output(in.Parameters)
+			return middleware.InitializeOutput{Result: result}, middleware.Metadata{}, err
+		}), middleware.After)
+}
+
+// Answer returns the same output to every call.
+func Answer(output interface{}) Output {
+	return func(interface{}) (interface{}, error) {
+		return output, nil
+	}
+}
+
+// Fail fails every call with the error.
+func Fail(err error) Output {
+	return func(interface{}) (interface{}, error) {
+		return nil, err
+	}
+}
+
+// DescribeInstances describes the instances given, by ID.
+func DescribeInstances(instances ...types.Instance) Output {
+	return func(input interface{}) (interface{}, error) {
+		output := &ec2.DescribeInstancesOutput{}
+		for _, id := range input.(*ec2.DescribeInstancesInput).InstanceIds {
+			for _, instance := range instances {
+				if aws.ToString(instance.InstanceId) == id {
+					output.Reservations = append(output.Reservations, types.Reservation{Instances: []types.Instance{instance}})
+				}
+			}
+		}
+		return output, nil
+	}
+}
+
+// Instance returns an instance in the given state.
+func Instance(id string, state types.InstanceStateName) types.Instance {
+	return types.Instance{
+		InstanceId:   aws.String(id),
+		InstanceType: types.InstanceTypeM5Large,
+		State:        &types.InstanceState{Name: state},
+	}
+}
@@ -0,0 +1,62 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+const arnPrefix = "arn:"
+
+// InstanceProfileSpecification refers to an instance profile given either by
+// name or by ARN.
+func InstanceProfileSpecification(profile string) *types.IamInstanceProfileSpecification {
+	if strings.HasPrefix(profile, arnPrefix) {
+		return &types.IamInstanceProfileSpecification{Arn: &profile}
+	}
+	return &types.IamInstanceProfileSpecification{Name: &profile}
+}
+
+// InstanceProfileMatches reports whether the instance profile attached to an
+// instance is the desired one. EC2 only reports the ARN, a desired name is
+// compared with its last segment, the ARN carrying the profile path.
+func InstanceProfileMatches(current *types.IamInstanceProfile, desired string) bool {
+	if current == nil || current.Arn == nil {
+		return desired == ""
+	}
+
+	arn := *current.Arn
+	if strings.HasPrefix(desired, arnPrefix) {
+		return arn == desired
+	}
+
+	return arn[strings.LastIndex(arn, "/")+1:] == desired
+}
+
+// GetIamInstanceProfileAssociation returns the instance profile association of
+// the instance, or nil when no profile is attached.
+func (e *EC2Client) GetIamInstanceProfileAssociation(ctx context.Context, instanceID string) (*types.IamInstanceProfileAssociation, error) {
+	output, err := e.Client.DescribeIamInstanceProfileAssociations(ctx, &ec2.DescribeIamInstanceProfileAssociationsInput{
+		Filters: []types.Filter{
+			{Name: aws.String("instance-id"), Values: []string{instanceID}},
+			{Name: aws.String("state"), Values: []string{
+				string(types.IamInstanceProfileAssociationStateAssociating),
+				string(types.IamInstanceProfileAssociationStateAssociated),
+				string(types.IamInstanceProfileAssociationStateDisassociating),
+			}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe instance profile association of ec2 instance %s: %w", instanceID, err)
+	}
+
+	if len(output.IamInstanceProfileAssociations) == 0 {
+		return nil, nil
+	}
+
+	return &output.IamInstanceProfileAssociations[0], nil
+}
@@ -0,0 +1,63 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestInstanceProfileMatches(t *testing.T) {
+	const arn = "arn:aws:iam::123456789012:instance-profile/team/web"
+
+	cases := map[string]struct {
+		reason  string
+		current *types.IamInstanceProfile
+		desired string
+		want    bool
+	}{
+		"NoneDesiredNoneAttached": {
+			reason: "No profile attached should match none desired.",
+			want:   true,
+		},
+		"NoneAttached": {
+			reason:  "No profile attached should not match a desired one.",
+			desired: "web",
+		},
+		"NoneDesired": {
+			reason:  "An attached profile should not match none desired.",
+			current: &types.IamInstanceProfile{Arn: aws.String(arn)},
+		},
+		"Name": {
+			reason:  "A desired name should match the last segment of the ARN, past the profile path.",
+			current: &types.IamInstanceProfile{Arn: aws.String(arn)},
+			desired: "web",
+			want:    true,
+		},
+		"OtherName": {
+			reason:  "Another name should not match.",
+			current: &types.IamInstanceProfile{Arn: aws.String(arn)},
+			desired: "batch",
+		},
+		"Arn": {
+			reason:  "A desired ARN should match the same ARN.",
+			current: &types.IamInstanceProfile{Arn: aws.String(arn)},
+			desired: arn,
+			want:    true,
+		},
+		"OtherArn": {
+			reason:  "A desired ARN should not match another path with the same name.",
+			current: &types.IamInstanceProfile{Arn: aws.String(arn)},
+			desired: "arn:aws:iam::123456789012:instance-profile/web",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, InstanceProfileMatches(tc.current, tc.desired)); diff != "" {
+				t.Errorf("\n%s\nInstanceProfileMatches(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
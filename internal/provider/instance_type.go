@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+)
+
+// CheckInstanceTypeCompatibility reports why the instance cannot be moved to
+// the instance type, comparing the architecture, virtualization type and ENA
+// requirement of the type with the image the instance was launched from. The
+// instance itself stands in for an image that is no longer available.
+func (e *EC2Client) CheckInstanceTypeCompatibility(ctx context.Context, instance *types.Instance, instanceType string) error {
+	typeOutput, err := e.Client.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{
+		InstanceTypes: []types.InstanceType{types.InstanceType(instanceType)},
+	})
+	if isInstanceTypeInvalid(err) || (err == nil && len(typeOutput.InstanceTypes) == 0) {
+		return fmt.Errorf("instance type %s does not exist", instanceType)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to describe instance type %s: %w", instanceType, err)
+	}
+	info := typeOutput.InstanceTypes[0]
+
+	architecture := string(instance.Architecture)
+	virtualization := instance.VirtualizationType
+	ena := aws.ToBool(instance.EnaSupport)
+
+	imageOutput, err := e.Client.DescribeImages(ctx, &ec2.DescribeImagesInput{
+		ImageIds: []string{aws.ToString(instance.ImageId)},
+	})
+	if err != nil && !isImageNotFound(err) {
+		return fmt.Errorf("failed to describe image %s: %w", aws.ToString(instance.ImageId), err)
+	}
+	if err == nil && len(imageOutput.Images) > 0 {
+		image := imageOutput.Images[0]
+		architecture = string(image.Architecture)
+		virtualization = image.VirtualizationType
+		ena = ena || aws.ToBool(image.EnaSupport)
+	}
+
+	if info.ProcessorInfo != nil && !slices.Contains(info.ProcessorInfo.SupportedArchitectures, types.ArchitectureType(architecture)) {
+		return fmt.Errorf("instance type %s does not support the %s architecture of image %s, supported: %v",
+			instanceType, architecture, aws.ToString(instance.ImageId), info.ProcessorInfo.SupportedArchitectures)
+	}
+
+	if virtualization != "" && !slices.Contains(info.SupportedVirtualizationTypes, virtualization) {
+		return fmt.Errorf("instance type %s does not support the %s virtualization of image %s, supported: %v",
+			instanceType, virtualization, aws.ToString(instance.ImageId), info.SupportedVirtualizationTypes)
+	}
+
+	if info.NetworkInfo != nil && info.NetworkInfo.EnaSupport == types.EnaSupportRequired && !ena {
+		return fmt.Errorf("instance type %s requires ENA, which image %s does not support",
+			instanceType, aws.ToString(instance.ImageId))
+	}
+
+	return nil
+}
+
+func isInstanceTypeInvalid(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidInstanceType"
+}
+
+func isImageNotFound(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	switch apiErr.ErrorCode() {
+	case "InvalidAMIID.NotFound", "InvalidAMIID.Unavailable":
+		return true
+	}
+	return false
+}
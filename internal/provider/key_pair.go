@@ -0,0 +1,177 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	"golang.org/x/crypto/ssh"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+const rsaKeyBits = 4096
+
+// GeneratedKeyName returns the name of the key pair generated for the
+// Compute. It is derived from its UID so a retried creation reuses it.
+func GeneratedKeyName(cr *v1alpha1.Compute) string {
+	return fmt.Sprintf("crossplane-%s", cr.GetUID())
+}
+
+// KeyName returns the name of the key pair the instance is launched with, if
+// any.
+func KeyName(cr *v1alpha1.Compute) string {
+	if cr.Spec.ForProvider.InstanceConfig.KeyPairGeneration != nil {
+		return GeneratedKeyName(cr)
+	}
+	return cr.Spec.ForProvider.InstanceConfig.KeyName
+}
+
+// ValidateKeyPair makes sure the key pair exists in the region of the client.
+func (e *EC2Client) ValidateKeyPair(ctx context.Context, keyName string) error {
+	_, err := e.Client.DescribeKeyPairs(ctx, &ec2.DescribeKeyPairsInput{
+		KeyNames: []string{keyName},
+	})
+	if isKeyPairNotFound(err) {
+		return fmt.Errorf("key pair %s does not exist", keyName)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to describe key pair %s: %w", keyName, err)
+	}
+
+	return nil
+}
+
+// ReusableKeyPair reports whether the key pair left by a previous creation
+// attempt can be reused: it carries the owner tag of the resource and, when a
+// private key is given, its public key matches it.
+func (e *EC2Client) ReusableKeyPair(ctx context.Context, keyName string, uid k8stypes.UID, privateKey []byte) (bool, error) {
+	output, err := e.Client.DescribeKeyPairs(ctx, &ec2.DescribeKeyPairsInput{
+		KeyNames:         []string{keyName},
+		IncludePublicKey: aws.Bool(true),
+	})
+	if isKeyPairNotFound(err) || (err == nil && len(output.KeyPairs) == 0) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to describe key pair %s: %w", keyName, err)
+	}
+
+	keyPair := output.KeyPairs[0]
+	owned := false
+	for _, tag := range keyPair.Tags {
+		if aws.ToString(tag.Key) == OwnerTagKey && aws.ToString(tag.Value) == string(uid) {
+			owned = true
+		}
+	}
+	if !owned || privateKey == nil {
+		return owned, nil
+	}
+
+	return publicKeyMatches(aws.ToString(keyPair.PublicKey), privateKey), nil
+}
+
+// ImportKeyPair imports the public key into EC2 under keyName, replacing any
+// key pair left by a previous attempt.
+func (e *EC2Client) ImportKeyPair(ctx context.Context, keyName string, publicKey []byte, uid k8stypes.UID) error {
+	if err := e.DeleteKeyPair(ctx, keyName); err != nil {
+		return err
+	}
+
+	_, err := e.Client.ImportKeyPair(ctx, &ec2.ImportKeyPairInput{
+		KeyName:           &keyName,
+		PublicKeyMaterial: publicKey,
+		TagSpecifications: []types.TagSpecification{
+			{
+				ResourceType: types.ResourceTypeKeyPair,
+				Tags:         []types.Tag{{Key: aws.String(OwnerTagKey), Value: aws.String(string(uid))}},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to import key pair %s: %w", keyName, err)
+	}
+
+	return nil
+}
+
+// DeleteKeyPair deletes the key pair. It is idempotent, EC2 does not complain
+// about a key pair that does not exist.
+func (e *EC2Client) DeleteKeyPair(ctx context.Context, keyName string) error {
+	_, err := e.Client.DeleteKeyPair(ctx, &ec2.DeleteKeyPairInput{
+		KeyName: &keyName,
+	})
+	if err != nil && !isKeyPairNotFound(err) {
+		return fmt.Errorf("failed to delete key pair %s: %w", keyName, err)
+	}
+
+	return nil
+}
+
+// GenerateKeyPair generates a key pair of the given type. It returns the
+// public key in the authorized keys format and the private key in the OpenSSH
+// PEM format.
+func GenerateKeyPair(keyType v1alpha1.KeyPairType) ([]byte, []byte, error) {
+	var (
+		public  crypto.PublicKey
+		private crypto.PrivateKey
+	)
+
+	switch keyType {
+	case v1alpha1.KeyPairTypeRSA:
+		key, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate rsa key: %w", err)
+		}
+		public, private = &key.PublicKey, key
+	case v1alpha1.KeyPairTypeED25519, "":
+		pub, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate ed25519 key: %w", err)
+		}
+		public, private = pub, key
+	default:
+		return nil, nil, fmt.Errorf("unsupported key pair type %q", keyType)
+	}
+
+	sshPublic, err := ssh.NewPublicKey(public)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	block, err := ssh.MarshalPrivateKey(private, "")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+
+	return ssh.MarshalAuthorizedKey(sshPublic), pem.EncodeToMemory(block), nil
+}
+
+func publicKeyMatches(publicKey string, privateKey []byte) bool {
+	public, _, _, _, err := ssh.ParseAuthorizedKey([]byte(publicKey))
+	if err != nil {
+		return false
+	}
+
+	signer, err := ssh.ParsePrivateKey(privateKey)
+	if err != nil {
+		return false
+	}
+
+	return bytes.Equal(public.Marshal(), signer.PublicKey().Marshal())
+}
+
+func isKeyPairNotFound(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidKeyPair.NotFound"
+}
@@ -0,0 +1,196 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider/fake"
+)
+
+func TestGenerateKeyPair(t *testing.T) {
+	type want struct {
+		matches bool
+		err     bool
+	}
+
+	cases := map[string]struct {
+		reason  string
+		keyType v1alpha1.KeyPairType
+		want    want
+	}{
+		"ED25519": {
+			reason:  "An ED25519 key pair should be generated.",
+			keyType: v1alpha1.KeyPairTypeED25519,
+			want:    want{matches: true},
+		},
+		"RSA": {
+			reason:  "An RSA key pair should be generated.",
+			keyType: v1alpha1.KeyPairTypeRSA,
+			want:    want{matches: true},
+		},
+		"Default": {
+			reason: "An ED25519 key pair should be generated when no type is given.",
+			want:   want{matches: true},
+		},
+		"Unsupported": {
+			reason:  "An unsupported key type should fail.",
+			keyType: "DSA",
+			want:    want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			public, private, err := GenerateKeyPair(tc.keyType)
+
+			got := want{matches: err == nil && publicKeyMatches(string(public), private), err: err != nil}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nGenerateKeyPair(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// describeKeyPair describes a key pair with the public key and owner tag.
+func describeKeyPair(publicKey []byte, owner string) fake.Output {
+	return fake.Answer(&ec2.DescribeKeyPairsOutput{KeyPairs: []types.KeyPairInfo{{
+		KeyName:   aws.String("crossplane-compute-uid"),
+		PublicKey: aws.String(string(publicKey)),
+		Tags:      []types.Tag{{Key: aws.String(OwnerTagKey), Value: aws.String(owner)}},
+	}}})
+}
+
+func TestReusableKeyPair(t *testing.T) {
+	public, private, err := GenerateKeyPair(v1alpha1.KeyPairTypeED25519)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair(...): %v", err)
+	}
+	_, other, err := GenerateKeyPair(v1alpha1.KeyPairTypeED25519)
+	if err != nil {
+		t.Fatalf("GenerateKeyPair(...): %v", err)
+	}
+
+	type want struct {
+		reusable bool
+		err      bool
+	}
+
+	cases := map[string]struct {
+		reason     string
+		describe   fake.Output
+		privateKey []byte
+		want       want
+	}{
+		"NotFound": {
+			reason:   "A key pair that does not exist should not be reused.",
+			describe: fake.Fail(&smithy.GenericAPIError{Code: "InvalidKeyPair.NotFound"}),
+			want:     want{},
+		},
+		"NotOwned": {
+			reason:     "A key pair owned by another resource should not be reused.",
+			describe:   describeKeyPair(public, "other-uid"),
+			privateKey: private,
+			want:       want{},
+		},
+		"OwnedWithoutPrivateKey": {
+			reason:   "An owned key pair should be reused when there is no private key to check it against.",
+			describe: describeKeyPair(public, "compute-uid"),
+			want:     want{reusable: true},
+		},
+		"OwnedMatching": {
+			reason:     "An owned key pair should be reused when its public key matches the private key.",
+			describe:   describeKeyPair(public, "compute-uid"),
+			privateKey: private,
+			want:       want{reusable: true},
+		},
+		"OwnedMismatching": {
+			reason:     "An owned key pair should not be reused when its public key does not match the private key.",
+			describe:   describeKeyPair(public, "compute-uid"),
+			privateKey: other,
+			want:       want{},
+		},
+		"DescribeError": {
+			reason:   "A failed description should be returned.",
+			describe: fake.Fail(&smithy.GenericAPIError{Code: "RequestLimitExceeded"}),
+			want:     want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fakeEC2 := &fake.EC2{Outputs: map[string]fake.Output{"DescribeKeyPairs": tc.describe}}
+			c := &EC2Client{Client: fakeEC2.Client()}
+
+			reusable, err := c.ReusableKeyPair(context.Background(), "crossplane-compute-uid", "compute-uid", tc.privateKey)
+
+			got := want{reusable: reusable, err: err != nil}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nReusableKeyPair(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestImportKeyPair(t *testing.T) {
+	type want struct {
+		calls []string
+		owner string
+		err   bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		delete fake.Output
+		want   want
+	}{
+		"Imported": {
+			reason: "The key pair should be imported with the owner tag, replacing the one left by a previous attempt.",
+			delete: fake.Answer(&ec2.DeleteKeyPairOutput{}),
+			want:   want{calls: []string{"DeleteKeyPair", "ImportKeyPair"}, owner: "compute-uid"},
+		},
+		"NothingToReplace": {
+			reason: "A key pair that does not exist yet should be imported.",
+			delete: fake.Fail(&smithy.GenericAPIError{Code: "InvalidKeyPair.NotFound"}),
+			want:   want{calls: []string{"DeleteKeyPair", "ImportKeyPair"}, owner: "compute-uid"},
+		},
+		"DeleteError": {
+			reason: "A key pair that cannot be replaced should not be imported.",
+			delete: fake.Fail(&smithy.GenericAPIError{Code: "UnauthorizedOperation"}),
+			want:   want{calls: []string{"DeleteKeyPair"}, err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var owner string
+			fakeEC2 := &fake.EC2{Outputs: map[string]fake.Output{
+				"DeleteKeyPair": tc.delete,
+				"ImportKeyPair": func(input interface{}) (interface{}, error) {
+					for _, spec := range input.(*ec2.ImportKeyPairInput).TagSpecifications {
+						for _, tag := range spec.Tags {
+							if aws.ToString(tag.Key) == OwnerTagKey {
+								owner = aws.ToString(tag.Value)
+							}
+						}
+					}
+					return &ec2.ImportKeyPairOutput{}, nil
+				},
+			}}
+			c := &EC2Client{Client: fakeEC2.Client()}
+
+			err := c.ImportKeyPair(context.Background(), "crossplane-compute-uid", []byte("ssh-ed25519 AAAA"), "compute-uid")
+
+			got := want{calls: fakeEC2.Calls, owner: owner, err: err != nil}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nImportKeyPair(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package provider
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+)
+
+// MetadataOptionsRequest maps the metadata options of the Compute to the
+// request sent to EC2, options left unset are not sent.
+func MetadataOptionsRequest(o *v1alpha1.MetadataOptions) *types.InstanceMetadataOptionsRequest {
+	if o == nil {
+		return nil
+	}
+
+	return &types.InstanceMetadataOptionsRequest{
+		HttpTokens:              types.HttpTokensState(o.HTTPTokens),
+		HttpPutResponseHopLimit: o.HTTPPutResponseHopLimit,
+		HttpEndpoint:            types.InstanceMetadataEndpointState(o.HTTPEndpoint),
+		InstanceMetadataTags:    types.InstanceMetadataTagsState(o.InstanceMetadataTags),
+	}
+}
+
+// MetadataOptionsMatch reports whether the metadata options of the instance
+// satisfy the desired ones. Options left unset match any value.
+func MetadataOptionsMatch(current *types.InstanceMetadataOptionsResponse, desired *v1alpha1.MetadataOptions) bool {
+	if desired == nil {
+		return true
+	}
+	if current == nil {
+		return false
+	}
+
+	switch {
+	case desired.HTTPTokens != "" && desired.HTTPTokens != string(current.HttpTokens):
+		return false
+	case desired.HTTPPutResponseHopLimit != nil && *desired.HTTPPutResponseHopLimit != aws.ToInt32(current.HttpPutResponseHopLimit):
+		return false
+	case desired.HTTPEndpoint != "" && desired.HTTPEndpoint != string(current.HttpEndpoint):
+		return false
+	case desired.InstanceMetadataTags != "" && desired.InstanceMetadataTags != string(current.InstanceMetadataTags):
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+)
+
+func TestMetadataOptionsMatch(t *testing.T) {
+	current := &types.InstanceMetadataOptionsResponse{
+		HttpTokens:              types.HttpTokensStateOptional,
+		HttpPutResponseHopLimit: aws.Int32(1),
+		HttpEndpoint:            types.InstanceMetadataEndpointStateEnabled,
+		InstanceMetadataTags:    types.InstanceMetadataTagsStateDisabled,
+	}
+
+	cases := map[string]struct {
+		reason  string
+		current *types.InstanceMetadataOptionsResponse
+		desired *v1alpha1.MetadataOptions
+		want    bool
+	}{
+		"Unmanaged": {
+			reason:  "Metadata options the Compute does not configure should always match.",
+			current: current,
+			want:    true,
+		},
+		"NotReported": {
+			reason:  "Metadata options EC2 does not report should not match configured ones.",
+			desired: &v1alpha1.MetadataOptions{HTTPTokens: "required"},
+		},
+		"Same": {
+			reason:  "The same options should match.",
+			current: current,
+			desired: &v1alpha1.MetadataOptions{
+				HTTPTokens:              "optional",
+				HTTPPutResponseHopLimit: aws.Int32(1),
+				HTTPEndpoint:            "enabled",
+				InstanceMetadataTags:    "disabled",
+			},
+			want: true,
+		},
+		"Unset": {
+			reason:  "Options left unset should match any value.",
+			current: current,
+			desired: &v1alpha1.MetadataOptions{HTTPEndpoint: "enabled"},
+			want:    true,
+		},
+		"HTTPTokensDrifted": {
+			reason:  "Session tokens no longer required should be detected.",
+			current: current,
+			desired: &v1alpha1.MetadataOptions{HTTPTokens: "required"},
+		},
+		"HopLimitDrifted": {
+			reason:  "A different hop limit should be detected.",
+			current: current,
+			desired: &v1alpha1.MetadataOptions{HTTPPutResponseHopLimit: aws.Int32(2)},
+		},
+		"EndpointDrifted": {
+			reason:  "A different endpoint state should be detected.",
+			current: current,
+			desired: &v1alpha1.MetadataOptions{HTTPEndpoint: "disabled"},
+		},
+		"TagsDrifted": {
+			reason:  "A different metadata tags state should be detected.",
+			current: current,
+			desired: &v1alpha1.MetadataOptions{InstanceMetadataTags: "enabled"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, MetadataOptionsMatch(tc.current, tc.desired)); diff != "" {
+				t.Errorf("\n%s\nMetadataOptionsMatch(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
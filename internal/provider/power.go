@@ -0,0 +1,49 @@
+package provider
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+)
+
+// DesiredPowerState returns the power state the instance is kept in, running
+// unless told otherwise.
+func DesiredPowerState(config *v1alpha1.InstanceConfig) v1alpha1.PowerState {
+	if config.PowerState == "" {
+		return v1alpha1.PowerStateRunning
+	}
+	return config.PowerState
+}
+
+// InPowerState reports whether the instance is in, or on its way to, the
+// power state. A hibernated instance is reported by EC2 as stopped.
+func InPowerState(instance *types.Instance, state v1alpha1.PowerState) bool {
+	switch instance.State.Name {
+	case types.InstanceStateNamePending, types.InstanceStateNameRunning:
+		return state == v1alpha1.PowerStateRunning
+	case types.InstanceStateNameStopping, types.InstanceStateNameStopped:
+		return state != v1alpha1.PowerStateRunning
+	}
+	return true
+}
+
+// ScheduledPowerState returns the power state of the instance once the override
+// set by a ComputeSchedule is applied. The schedule only parks instances the
+// spec keeps running, and stops those it cannot hibernate.
+func ScheduledPowerState(cr *v1alpha1.Compute, config *v1alpha1.InstanceConfig) v1alpha1.PowerState {
+	desired := DesiredPowerState(config)
+	if desired != v1alpha1.PowerStateRunning {
+		return desired
+	}
+
+	switch override := v1alpha1.PowerState(cr.GetAnnotations()[v1alpha1.AnnotationKeyPowerState]); override {
+	case v1alpha1.PowerStateStopped:
+		return override
+	case v1alpha1.PowerStateHibernated:
+		if config.HibernationEnabled {
+			return override
+		}
+		return v1alpha1.PowerStateStopped
+	}
+
+	return desired
+}
@@ -0,0 +1,76 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider/fake"
+)
+
+func TestInPowerState(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		state  types.InstanceStateName
+		power  v1alpha1.PowerState
+		want   bool
+	}{
+		"Running": {
+			reason: "A running instance should be in the running state.",
+			state:  types.InstanceStateNameRunning,
+			power:  v1alpha1.PowerStateRunning,
+			want:   true,
+		},
+		"Pending": {
+			reason: "A starting instance should be on its way to the running state.",
+			state:  types.InstanceStateNamePending,
+			power:  v1alpha1.PowerStateRunning,
+			want:   true,
+		},
+		"RunningMeantStopped": {
+			reason: "A running instance should not be in the stopped state.",
+			state:  types.InstanceStateNameRunning,
+			power:  v1alpha1.PowerStateStopped,
+		},
+		"Stopped": {
+			reason: "A stopped instance should be in the stopped state.",
+			state:  types.InstanceStateNameStopped,
+			power:  v1alpha1.PowerStateStopped,
+			want:   true,
+		},
+		"Hibernated": {
+			reason: "A hibernated instance, reported as stopped, should be in the hibernated state.",
+			state:  types.InstanceStateNameStopped,
+			power:  v1alpha1.PowerStateHibernated,
+			want:   true,
+		},
+		"Stopping": {
+			reason: "A stopping instance should be on its way to the hibernated state.",
+			state:  types.InstanceStateNameStopping,
+			power:  v1alpha1.PowerStateHibernated,
+			want:   true,
+		},
+		"StoppedMeantRunning": {
+			reason: "A stopped instance should not be in the running state.",
+			state:  types.InstanceStateNameStopped,
+			power:  v1alpha1.PowerStateRunning,
+		},
+		"Terminated": {
+			reason: "A terminated instance should not be reported as out of its power state.",
+			state:  types.InstanceStateNameTerminated,
+			power:  v1alpha1.PowerStateRunning,
+			want:   true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			instance := fake.Instance("i-1", tc.state)
+			if diff := cmp.Diff(tc.want, InPowerState(&instance, tc.power)); diff != "" {
+				t.Errorf("\n%s\nInPowerState(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
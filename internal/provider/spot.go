@@ -0,0 +1,96 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+)
+
+// spotInterruptionPrefix starts the state reason codes EC2 sets on a Spot
+// instance it stopped, hibernated or terminated to reclaim capacity.
+const spotInterruptionPrefix = "Server.SpotInstance"
+
+var (
+	spotInstanceTypes = map[v1alpha1.SpotInstanceType]types.SpotInstanceType{
+		v1alpha1.SpotInstanceTypeOneTime:    types.SpotInstanceTypeOneTime,
+		v1alpha1.SpotInstanceTypePersistent: types.SpotInstanceTypePersistent,
+	}
+
+	interruptionBehaviors = map[v1alpha1.InterruptionBehavior]types.InstanceInterruptionBehavior{
+		v1alpha1.InterruptionBehaviorTerminate: types.InstanceInterruptionBehaviorTerminate,
+		v1alpha1.InterruptionBehaviorStop:      types.InstanceInterruptionBehaviorStop,
+		v1alpha1.InterruptionBehaviorHibernate: types.InstanceInterruptionBehaviorHibernate,
+	}
+)
+
+// instanceMarketOptions maps the market options of the Compute to the
+// RunInstances request. On-Demand instances need none.
+func instanceMarketOptions(o *v1alpha1.MarketOptions) *types.InstanceMarketOptionsRequest {
+	if o == nil || o.MarketType != v1alpha1.MarketTypeSpot {
+		return nil
+	}
+
+	spot := &types.SpotMarketOptions{
+		SpotInstanceType:             spotInstanceTypes[o.SpotInstanceType],
+		InstanceInterruptionBehavior: interruptionBehaviors[o.InterruptionBehavior],
+	}
+	if o.MaxPrice != "" {
+		spot.MaxPrice = &o.MaxPrice
+	}
+
+	return &types.InstanceMarketOptionsRequest{
+		MarketType:  types.MarketTypeSpot,
+		SpotOptions: spot,
+	}
+}
+
+// IsSpotInterruption reports whether EC2 reclaimed the Spot instance.
+func IsSpotInterruption(instance *types.Instance) bool {
+	if instance.InstanceLifecycle != types.InstanceLifecycleTypeSpot || instance.StateReason == nil {
+		return false
+	}
+	return strings.HasPrefix(aws.ToString(instance.StateReason.Code), spotInterruptionPrefix)
+}
+
+// SurvivingVolumes returns the volumes among the given ones that outlive their
+// instance, leaving out those deleted, or about to be, along with it.
+func (e *EC2Client) SurvivingVolumes(ctx context.Context, volumeIDs []string) ([]types.Volume, error) {
+	if len(volumeIDs) == 0 {
+		return nil, nil
+	}
+
+	// Filtering on the IDs, rather than asking for them, does not fail on the
+	// volumes that are gone.
+	output, err := e.Client.DescribeVolumes(ctx, &ec2.DescribeVolumesInput{
+		Filters: []types.Filter{
+			{Name: aws.String("volume-id"), Values: volumeIDs},
+			{Name: aws.String("status"), Values: []string{
+				string(types.VolumeStateAvailable),
+				string(types.VolumeStateInUse),
+			}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe volumes %v: %w", volumeIDs, err)
+	}
+
+	var volumes []types.Volume
+	for _, volume := range output.Volumes {
+		deleted := false
+		for _, attachment := range volume.Attachments {
+			if aws.ToBool(attachment.DeleteOnTermination) {
+				deleted = true
+			}
+		}
+		if !deleted {
+			volumes = append(volumes, volume)
+		}
+	}
+
+	return volumes, nil
+}
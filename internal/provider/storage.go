@@ -0,0 +1,41 @@
+package provider
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+)
+
+// DeleteOnTermination reports whether the volume goes away with the instance.
+// Volumes are deleted with their instance unless told otherwise.
+func DeleteOnTermination(storage v1alpha1.Storage) bool {
+	return storage.DeleteOnTermination == nil || *storage.DeleteOnTermination
+}
+
+// Encrypted reports whether the volume is created encrypted, which it is
+// unless told otherwise.
+func Encrypted(storage v1alpha1.Storage) bool {
+	return storage.Encrypted == nil || *storage.Encrypted
+}
+
+// EBSBlockDevice maps a storage entry to the EBS volume launched with the
+// instance.
+func EBSBlockDevice(storage v1alpha1.Storage) *types.EbsBlockDevice {
+	ebs := &types.EbsBlockDevice{
+		DeleteOnTermination: aws.Bool(DeleteOnTermination(storage)),
+		Encrypted:           aws.Bool(Encrypted(storage)),
+		VolumeType:          types.VolumeType(storage.InstanceDisk),
+		VolumeSize:          aws.Int32(storage.DiskSize),
+		Iops:                storage.IOPS,
+		Throughput:          storage.Throughput,
+	}
+
+	if storage.KMSKeyID != "" {
+		ebs.KmsKeyId = aws.String(storage.KMSKeyID)
+	}
+	if storage.SnapshotID != "" {
+		ebs.SnapshotId = aws.String(storage.SnapshotID)
+	}
+
+	return ebs
+}
@@ -1,23 +1,48 @@
 package shared
 
 import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
 	"github.com/crossplane/provider-customcomputeprovider/internal/commands/volume"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
 )
 
 type VolumeInformation struct {
-	VolumeID   string
-	VolumeType string
-	VolumeSize int32
-	DeviceName string
+	VolumeID            string
+	VolumeType          string
+	VolumeSize          int32
+	DeviceName          string
+	IOPS                int32
+	Throughput          int32
+	DeleteOnTermination bool
+	AttachmentState     types.VolumeAttachmentState
 }
 
 type VolumeState struct {
 	Current  map[string]VolumeInformation
 	Desired  []v1alpha1.Storage
 	Instance *types.Instance
+
+	// Tracked are the volumes recorded for the storage entries, keyed by
+	// their device name.
+	Tracked map[string]v1alpha1.StorageVolume
+}
+
+// VolumeShrink is a storage entry asking for a volume smaller than the one
+// backing it.
+type VolumeShrink struct {
+	DeviceName  string
+	VolumeID    string
+	CurrentSize int32
+	DesiredSize int32
+
+	// Replace is set when the volume is replaced by a smaller one, the shrink
+	// is refused otherwise.
+	Replace bool
 }
 
 type CommandAnalyzer struct {
@@ -38,10 +63,14 @@ func (a *CommandAnalyzer) BuildVolumeState(output *ec2.DescribeVolumesOutput, in
 			volumeType := string(volume.VolumeType)
 
 			current[volumeDeviceName] = VolumeInformation{
-				VolumeID:   volumeID,
-				VolumeType: volumeType,
-				VolumeSize: volumeSize,
-				DeviceName: volumeDeviceName,
+				VolumeID:            volumeID,
+				VolumeType:          volumeType,
+				VolumeSize:          volumeSize,
+				DeviceName:          volumeDeviceName,
+				IOPS:                aws.ToInt32(volume.Iops),
+				Throughput:          aws.ToInt32(volume.Throughput),
+				DeleteOnTermination: aws.ToBool(volume.Attachments[0].DeleteOnTermination),
+				AttachmentState:     volume.Attachments[0].State,
 			}
 		}
 	}
@@ -52,62 +81,198 @@ func (a *CommandAnalyzer) BuildVolumeState(output *ec2.DescribeVolumesOutput, in
 	}
 }
 
+// MatchVolumes pairs the storage entries with the attached volumes backing
+// them, keyed by the device name of the entry. Pinned and tracked volume IDs
+// are matched first, the device name is only used to adopt a volume that is
+// not tracked yet.
+func (a *CommandAnalyzer) MatchVolumes(volumeState *VolumeState) map[string]VolumeInformation {
+	byID := make(map[string]VolumeInformation, len(volumeState.Current))
+	for _, current := range volumeState.Current {
+		byID[current.VolumeID] = current
+	}
+
+	matches := make(map[string]VolumeInformation, len(volumeState.Desired))
+	claimed := make(map[string]bool, len(volumeState.Current))
+
+	for _, desired := range volumeState.Desired {
+		volumeID := desired.VolumeID
+		if volumeID == "" {
+			volumeID = volumeState.Tracked[desired.DeviceName].VolumeID
+		}
+
+		if current, found := byID[volumeID]; found && volumeID != "" {
+			matches[desired.DeviceName] = current
+			claimed[volumeID] = true
+		}
+	}
+
+	for _, desired := range volumeState.Desired {
+		if _, found := matches[desired.DeviceName]; found || desired.VolumeID != "" {
+			continue
+		}
+
+		for _, current := range volumeState.Current {
+			if !claimed[current.VolumeID] && sameDevice(current.DeviceName, desired.DeviceName) {
+				matches[desired.DeviceName] = current
+				claimed[current.VolumeID] = true
+				break
+			}
+		}
+	}
+
+	return matches
+}
+
+// sameDevice compares device names the way EC2 treats them, /dev/sdf and
+// /dev/xvdf name the same device.
+func sameDevice(a, b string) bool {
+	normalize := func(device string) string {
+		device = strings.TrimPrefix(device, "/dev/")
+		if strings.HasPrefix(device, "xvd") {
+			return "sd" + strings.TrimPrefix(device, "xvd")
+		}
+		return device
+	}
+	return normalize(a) == normalize(b)
+}
+
+// AnalyzeShrinks returns the storage entries asking for a smaller volume.
+// Only a volume the provider created, other than the root one, can be
+// replaced.
+func (a *CommandAnalyzer) AnalyzeShrinks(volumeState *VolumeState) []VolumeShrink {
+	matches := a.MatchVolumes(volumeState)
+
+	var shrinks []VolumeShrink
+	for _, desired := range volumeState.Desired {
+		current, exists := matches[desired.DeviceName]
+		if !exists || desired.DiskSize >= current.VolumeSize {
+			continue
+		}
+
+		shrinks = append(shrinks, VolumeShrink{
+			DeviceName:  desired.DeviceName,
+			VolumeID:    current.VolumeID,
+			CurrentSize: current.VolumeSize,
+			DesiredSize: desired.DiskSize,
+			Replace: desired.AllowReplaceOnShrink && desired.VolumeID == "" &&
+				current.DeviceName != aws.ToString(volumeState.Instance.RootDeviceName),
+		})
+	}
+
+	return shrinks
+}
+
 func (a *CommandAnalyzer) AnalyzeChanges(volumeState *VolumeState) []volume.VolumeCommand {
 	var cmds []volume.VolumeCommand
 
+	matches := a.MatchVolumes(volumeState)
+
+	// A volume replaced to shrink it is detached, snapshotted and retained,
+	// the storage entry is then served by a new, empty, volume like a new
+	// entry.
+	replaced := make(map[string]bool)
+	for _, shrink := range a.AnalyzeShrinks(volumeState) {
+		if shrink.Replace {
+			replaced[shrink.DeviceName] = true
+		}
+	}
+
 	for _, desired := range volumeState.Desired {
-		current, exists := volumeState.Current[desired.DeviceName]
+		current, exists := matches[desired.DeviceName]
 		if !exists {
 			cmds = append(cmds, volume.NewVolumeCommand(
 				*volumeState.Instance.InstanceId,
 				*volumeState.Instance.SubnetId,
-				desired.DeviceName,
-				desired.InstanceDisk,
-				desired.DiskSize,
+				desired,
 			))
 			continue
 		}
 
+		if replaced[desired.DeviceName] {
+			detach := volume.NewDetachVolumeCommand(
+				current.VolumeID,
+				current.DeviceName,
+				*volumeState.Instance.InstanceId,
+				v1alpha1.RemovalPolicyRetain,
+				current.AttachmentState,
+			)
+			detach.Snapshot = true
+			cmds = append(cmds, detach)
+			continue
+		}
+
 		volumeChangeCommand := a.analyzeVolumeChanges(current, desired)
 		cmds = append(cmds, volumeChangeCommand...)
+
+		if desired.VolumeID != "" {
+			continue
+		}
+
+		if deleteOnTermination := provider.DeleteOnTermination(desired); deleteOnTermination != current.DeleteOnTermination {
+			cmds = append(cmds, volume.NewDeleteOnTerminationCommand(
+				*volumeState.Instance.InstanceId,
+				desired.DeviceName,
+				deleteOnTermination,
+			))
+		}
 	}
 
-	volumeAttachCommands := a.analyzeDetachments(*volumeState)
+	volumeAttachCommands := a.analyzeDetachments(*volumeState, matches)
 	cmds = append(cmds, volumeAttachCommands...)
 	return cmds
 }
 
 func (a *CommandAnalyzer) analyzeVolumeChanges(vi VolumeInformation, dv v1alpha1.Storage) []volume.VolumeCommand {
-	var commands []volume.VolumeCommand
+	cmd := volume.NewModifyVolumeCommand(vi.VolumeID)
 	if dv.DiskSize > vi.VolumeSize {
-		updateVolumeCommand := volume.NewUpdateVolumeCommand(vi.VolumeID, dv.DiskSize)
-		commands = append(commands, updateVolumeCommand)
+		cmd.DiskSize = &dv.DiskSize
 	}
 
 	if dv.InstanceDisk != vi.VolumeType {
-		updateVolumeTypeCommand := volume.NewUpdateVolumeTypeCommand(vi.VolumeID, vi.VolumeType)
-		commands = append(commands, updateVolumeTypeCommand)
+		cmd.VolumeType = dv.InstanceDisk
 	}
-	return commands
+
+	if dv.IOPS != nil && *dv.IOPS != vi.IOPS {
+		cmd.IOPS = dv.IOPS
+	}
+
+	if dv.Throughput != nil && *dv.Throughput != vi.Throughput {
+		cmd.Throughput = dv.Throughput
+	}
+
+	if !cmd.HasChanges() {
+		return nil
+	}
+	return []volume.VolumeCommand{cmd}
 }
 
-func (a *CommandAnalyzer) analyzeDetachments(state VolumeState) []volume.VolumeCommand {
+func (a *CommandAnalyzer) analyzeDetachments(state VolumeState, matches map[string]VolumeInformation) []volume.VolumeCommand {
 	var commands []volume.VolumeCommand
 
-	for deviceName, current := range state.Current {
-		found := false
-		for _, desired := range state.Desired {
-			if desired.DeviceName == deviceName {
-				found = true
-				break
-			}
-		}
+	matched := make(map[string]bool, len(matches))
+	for _, vi := range matches {
+		matched[vi.VolumeID] = true
+	}
+
+	// The entry of a removed volume is gone from the spec, its removal policy
+	// is the one recorded along with the volume.
+	policies := make(map[string]v1alpha1.RemovalPolicy, len(state.Tracked))
+	for _, tracked := range state.Tracked {
+		policies[tracked.VolumeID] = tracked.RemovalPolicy
+	}
+
+	// The root volume boots the instance, it is left attached when the spec
+	// does not list it.
+	rootDevice := aws.ToString(state.Instance.RootDeviceName)
 
-		if !found {
+	for _, current := range state.Current {
+		if !matched[current.VolumeID] && !sameDevice(current.DeviceName, rootDevice) {
 			commands = append(commands, volume.NewDetachVolumeCommand(
 				current.VolumeID,
 				current.DeviceName,
 				*state.Instance.InstanceId,
+				policies[current.VolumeID],
+				current.AttachmentState,
 			))
 		}
 	}
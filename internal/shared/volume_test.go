@@ -0,0 +1,372 @@
+package shared
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	"github.com/crossplane/provider-customcomputeprovider/internal/commands/volume"
+)
+
+func instance() *types.Instance {
+	return &types.Instance{
+		InstanceId:     aws.String("i-1"),
+		SubnetId:       aws.String("subnet-1"),
+		RootDeviceName: aws.String("/dev/xvda"),
+	}
+}
+
+func TestSameDevice(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		a, b   string
+		want   bool
+	}{
+		"Identical": {
+			reason: "A device name should match itself.",
+			a:      "/dev/sdf",
+			b:      "/dev/sdf",
+			want:   true,
+		},
+		"XenAlias": {
+			reason: "EC2 reports /dev/sdX devices as /dev/xvdX on Xen instances.",
+			a:      "/dev/sdf",
+			b:      "/dev/xvdf",
+			want:   true,
+		},
+		"WithoutPrefix": {
+			reason: "The /dev/ prefix should not matter.",
+			a:      "xvdf",
+			b:      "/dev/sdf",
+			want:   true,
+		},
+		"OtherLetter": {
+			reason: "Devices with another letter should not match.",
+			a:      "/dev/sdf",
+			b:      "/dev/xvdg",
+			want:   false,
+		},
+		"Partition": {
+			reason: "A partition should not match another one.",
+			a:      "/dev/sda1",
+			b:      "/dev/xvda",
+			want:   false,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := sameDevice(tc.a, tc.b); got != tc.want {
+				t.Errorf("\n%s\nsameDevice(%q, %q): want %t, got %t\n", tc.reason, tc.a, tc.b, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestMatchVolumes(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		state  *VolumeState
+		want   map[string]string
+	}{
+		"ByDevice": {
+			reason: "An untracked volume should be adopted by the entry of its device, whatever its alias.",
+			state: &VolumeState{
+				Current: map[string]VolumeInformation{
+					"/dev/xvdf": {VolumeID: "vol-1", DeviceName: "/dev/xvdf"},
+				},
+				Desired: []v1alpha1.Storage{{DeviceName: "/dev/sdf"}},
+			},
+			want: map[string]string{"/dev/sdf": "vol-1"},
+		},
+		"Pinned": {
+			reason: "A pinned volume should be matched by its ID wherever it is attached.",
+			state: &VolumeState{
+				Current: map[string]VolumeInformation{
+					"/dev/sdh": {VolumeID: "vol-2", DeviceName: "/dev/sdh"},
+				},
+				Desired: []v1alpha1.Storage{{DeviceName: "/dev/sdg", VolumeID: "vol-2"}},
+			},
+			want: map[string]string{"/dev/sdg": "vol-2"},
+		},
+		"PinnedNotAttached": {
+			reason: "A pinned volume that is not attached should not fall back to the volume on its device.",
+			state: &VolumeState{
+				Current: map[string]VolumeInformation{
+					"/dev/sdg": {VolumeID: "vol-3", DeviceName: "/dev/sdg"},
+				},
+				Desired: []v1alpha1.Storage{{DeviceName: "/dev/sdg", VolumeID: "vol-2"}},
+			},
+			want: map[string]string{},
+		},
+		"Tracked": {
+			reason: "A tracked volume should be matched before the volume found on the device.",
+			state: &VolumeState{
+				Current: map[string]VolumeInformation{
+					"/dev/sdf": {VolumeID: "vol-4", DeviceName: "/dev/sdf"},
+					"/dev/sdg": {VolumeID: "vol-5", DeviceName: "/dev/sdg"},
+				},
+				Desired: []v1alpha1.Storage{{DeviceName: "/dev/sdf"}},
+				Tracked: map[string]v1alpha1.StorageVolume{"/dev/sdf": {VolumeID: "vol-5"}},
+			},
+			want: map[string]string{"/dev/sdf": "vol-5"},
+		},
+		"Claimed": {
+			reason: "A volume matched by ID should not be adopted by another entry.",
+			state: &VolumeState{
+				Current: map[string]VolumeInformation{
+					"/dev/sdf": {VolumeID: "vol-6", DeviceName: "/dev/sdf"},
+				},
+				Desired: []v1alpha1.Storage{
+					{DeviceName: "/dev/sdf"},
+					{DeviceName: "/dev/sdg", VolumeID: "vol-6"},
+				},
+			},
+			want: map[string]string{"/dev/sdg": "vol-6"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := map[string]string{}
+			for device, vi := range NewCommandAnalyzer().MatchVolumes(tc.state) {
+				got[device] = vi.VolumeID
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nMatchVolumes(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// describeCommands names the commands with the volume or device they apply
+// to, sorted.
+func describeCommands(cmds []volume.VolumeCommand) []string {
+	var described []string
+	for _, cmd := range cmds {
+		switch c := cmd.(type) {
+		case *volume.CreateVolumeCommand:
+			described = append(described, c.GetType()+" "+c.DeviceName)
+		case *volume.ModifyVolumeCommand:
+			modify := c.GetType() + " " + c.VolumeID
+			if c.DiskSize != nil {
+				modify += fmt.Sprintf(" size=%d", *c.DiskSize)
+			}
+			if c.VolumeType != "" {
+				modify += " type=" + c.VolumeType
+			}
+			if c.IOPS != nil {
+				modify += fmt.Sprintf(" iops=%d", *c.IOPS)
+			}
+			if c.Throughput != nil {
+				modify += fmt.Sprintf(" throughput=%d", *c.Throughput)
+			}
+			described = append(described, modify)
+		case *volume.DetachVolumeCommand:
+			detach := c.GetType() + " " + c.VolumeId + " " + string(c.RemovalPolicy)
+			if c.Snapshot {
+				detach += " Snapshot"
+			}
+			described = append(described, detach)
+		case *volume.DeleteOnTerminationCommand:
+			described = append(described, c.GetType()+" "+c.DeviceName)
+		default:
+			described = append(described, c.GetType())
+		}
+	}
+	sort.Strings(described)
+	return described
+}
+
+func TestAnalyzeChanges(t *testing.T) {
+	root := VolumeInformation{VolumeID: "vol-root", DeviceName: "/dev/xvda", VolumeSize: 8, VolumeType: "gp3", DeleteOnTermination: true}
+	data := VolumeInformation{VolumeID: "vol-data", DeviceName: "/dev/sdf", VolumeSize: 100, VolumeType: "gp3", IOPS: 3000, Throughput: 125, DeleteOnTermination: true}
+
+	cases := map[string]struct {
+		reason  string
+		current map[string]VolumeInformation
+		desired []v1alpha1.Storage
+		want    []string
+	}{
+		"UpToDate": {
+			reason:  "Volumes matching their entries should need no command.",
+			current: map[string]VolumeInformation{"/dev/xvda": root, "/dev/sdf": data},
+			desired: []v1alpha1.Storage{
+				{DeviceName: "/dev/xvda", DiskSize: 8, InstanceDisk: "gp3"},
+				{DeviceName: "/dev/sdf", DiskSize: 100, InstanceDisk: "gp3"},
+			},
+		},
+		"RootUnlisted": {
+			reason:  "The root volume should be left attached when the spec does not list it.",
+			current: map[string]VolumeInformation{"/dev/xvda": root, "/dev/sdf": data},
+			desired: []v1alpha1.Storage{{DeviceName: "/dev/sdf", DiskSize: 100, InstanceDisk: "gp3"}},
+		},
+		"RootAlias": {
+			reason:  "A root entry naming the device by its other alias should match the root volume.",
+			current: map[string]VolumeInformation{"/dev/xvda": root},
+			desired: []v1alpha1.Storage{{DeviceName: "/dev/sda", DiskSize: 16, InstanceDisk: "gp3"}},
+			want:    []string{"ModifyVolume vol-root size=16"},
+		},
+		"RootShrink": {
+			reason:  "A smaller root volume should not be replaced, even when allowed.",
+			current: map[string]VolumeInformation{"/dev/xvda": root},
+			desired: []v1alpha1.Storage{
+				{DeviceName: "/dev/xvda", DiskSize: 4, InstanceDisk: "gp3", AllowReplaceOnShrink: true},
+			},
+		},
+		"Grow": {
+			reason:  "A larger or retyped volume should be modified.",
+			current: map[string]VolumeInformation{"/dev/sdf": data},
+			desired: []v1alpha1.Storage{{DeviceName: "/dev/sdf", DiskSize: 200, InstanceDisk: "io2"}},
+			want:    []string{"ModifyVolume vol-data size=200 type=io2"},
+		},
+		"VolumeType": {
+			reason:  "A retyped volume should be modified with its new type only.",
+			current: map[string]VolumeInformation{"/dev/sdf": data},
+			desired: []v1alpha1.Storage{{DeviceName: "/dev/sdf", DiskSize: 100, InstanceDisk: "io1"}},
+			want:    []string{"ModifyVolume vol-data type=io1"},
+		},
+		"IOPS": {
+			reason:  "Changed IOPS should be modified.",
+			current: map[string]VolumeInformation{"/dev/sdf": data},
+			desired: []v1alpha1.Storage{{DeviceName: "/dev/sdf", DiskSize: 100, InstanceDisk: "gp3", IOPS: aws.Int32(6000)}},
+			want:    []string{"ModifyVolume vol-data iops=6000"},
+		},
+		"Throughput": {
+			reason:  "Changed throughput should be modified.",
+			current: map[string]VolumeInformation{"/dev/sdf": data},
+			desired: []v1alpha1.Storage{{DeviceName: "/dev/sdf", DiskSize: 100, InstanceDisk: "gp3", Throughput: aws.Int32(500)}},
+			want:    []string{"ModifyVolume vol-data throughput=500"},
+		},
+		"PerformanceUpToDate": {
+			reason:  "IOPS and throughput matching the volume should need no command.",
+			current: map[string]VolumeInformation{"/dev/sdf": data},
+			desired: []v1alpha1.Storage{
+				{DeviceName: "/dev/sdf", DiskSize: 100, InstanceDisk: "gp3", IOPS: aws.Int32(3000), Throughput: aws.Int32(125)},
+			},
+		},
+		"AllAttributes": {
+			reason:  "Every changed attribute of a volume should be sent in a single modification.",
+			current: map[string]VolumeInformation{"/dev/sdf": data},
+			desired: []v1alpha1.Storage{
+				{DeviceName: "/dev/sdf", DiskSize: 200, InstanceDisk: "io2", IOPS: aws.Int32(8000), Throughput: aws.Int32(500)},
+			},
+			want: []string{"ModifyVolume vol-data size=200 type=io2 iops=8000 throughput=500"},
+		},
+		"ShrinkReplaced": {
+			reason:  "A smaller data volume allowed to be replaced should be detached, snapshotted and retained.",
+			current: map[string]VolumeInformation{"/dev/sdf": data},
+			desired: []v1alpha1.Storage{
+				{DeviceName: "/dev/sdf", DiskSize: 50, InstanceDisk: "gp3", AllowReplaceOnShrink: true},
+			},
+			want: []string{"DetachVolume vol-data Retain Snapshot"},
+		},
+		"New": {
+			reason:  "An entry without a volume should create one.",
+			current: map[string]VolumeInformation{"/dev/xvda": root},
+			desired: []v1alpha1.Storage{{DeviceName: "/dev/sdg", DiskSize: 10, InstanceDisk: "gp3"}},
+			want:    []string{"CreateVolume /dev/sdg"},
+		},
+		"Removed": {
+			reason:  "A data volume whose entry is gone should be detached.",
+			current: map[string]VolumeInformation{"/dev/xvda": root, "/dev/sdf": data},
+			desired: []v1alpha1.Storage{{DeviceName: "/dev/xvda", DiskSize: 8, InstanceDisk: "gp3"}},
+			want:    []string{"DetachVolume vol-data Retain"},
+		},
+		"DeleteOnTermination": {
+			reason:  "A changed deleteOnTermination should be applied to the attachment.",
+			current: map[string]VolumeInformation{"/dev/sdf": data},
+			desired: []v1alpha1.Storage{
+				{DeviceName: "/dev/sdf", DiskSize: 100, InstanceDisk: "gp3", DeleteOnTermination: aws.Bool(false)},
+			},
+			want: []string{"DeleteOnTermination /dev/sdf"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			state := &VolumeState{Current: tc.current, Desired: tc.desired, Instance: instance()}
+			got := describeCommands(NewCommandAnalyzer().AnalyzeChanges(state))
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nAnalyzeChanges(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestAnalyzeShrinks(t *testing.T) {
+	root := VolumeInformation{VolumeID: "vol-root", DeviceName: "/dev/xvda", VolumeSize: 8}
+	data := VolumeInformation{VolumeID: "vol-data", DeviceName: "/dev/sdf", VolumeSize: 100}
+
+	cases := map[string]struct {
+		reason  string
+		current map[string]VolumeInformation
+		desired []v1alpha1.Storage
+		want    []VolumeShrink
+	}{
+		"Shrink": {
+			reason:  "A smaller volume should be refused unless allowed to be replaced.",
+			current: map[string]VolumeInformation{"/dev/sdf": data},
+			desired: []v1alpha1.Storage{{DeviceName: "/dev/sdf", DiskSize: 50}},
+			want: []VolumeShrink{
+				{DeviceName: "/dev/sdf", VolumeID: "vol-data", CurrentSize: 100, DesiredSize: 50},
+			},
+		},
+		"ShrinkReplaced": {
+			reason:  "A smaller volume allowed to be replaced should be.",
+			current: map[string]VolumeInformation{"/dev/sdf": data},
+			desired: []v1alpha1.Storage{{DeviceName: "/dev/sdf", DiskSize: 50, AllowReplaceOnShrink: true}},
+			want: []VolumeShrink{
+				{DeviceName: "/dev/sdf", VolumeID: "vol-data", CurrentSize: 100, DesiredSize: 50, Replace: true},
+			},
+		},
+		"ShrinkPinned": {
+			reason:  "A pinned volume should never be replaced.",
+			current: map[string]VolumeInformation{"/dev/sdf": data},
+			desired: []v1alpha1.Storage{
+				{DeviceName: "/dev/sdf", VolumeID: "vol-data", DiskSize: 50, AllowReplaceOnShrink: true},
+			},
+			want: []VolumeShrink{
+				{DeviceName: "/dev/sdf", VolumeID: "vol-data", CurrentSize: 100, DesiredSize: 50},
+			},
+		},
+		"ShrinkRoot": {
+			reason:  "The root volume should never be replaced.",
+			current: map[string]VolumeInformation{"/dev/xvda": root},
+			desired: []v1alpha1.Storage{{DeviceName: "/dev/xvda", DiskSize: 4, AllowReplaceOnShrink: true}},
+			want: []VolumeShrink{
+				{DeviceName: "/dev/xvda", VolumeID: "vol-root", CurrentSize: 8, DesiredSize: 4},
+			},
+		},
+		"Grow": {
+			reason:  "A larger volume should not be a shrink.",
+			current: map[string]VolumeInformation{"/dev/sdf": data},
+			desired: []v1alpha1.Storage{{DeviceName: "/dev/sdf", DiskSize: 200}},
+		},
+		"Unchanged": {
+			reason:  "A volume of the desired size should not be a shrink.",
+			current: map[string]VolumeInformation{"/dev/sdf": data},
+			desired: []v1alpha1.Storage{{DeviceName: "/dev/sdf", DiskSize: 100}},
+		},
+		"Missing": {
+			reason:  "An entry without a volume should not be a shrink.",
+			current: map[string]VolumeInformation{"/dev/xvda": root},
+			desired: []v1alpha1.Storage{{DeviceName: "/dev/sdf", DiskSize: 50}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			state := &VolumeState{Current: tc.current, Desired: tc.desired, Instance: instance()}
+			got := NewCommandAnalyzer().AnalyzeShrinks(state)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nAnalyzeShrinks(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -8,6 +8,10 @@ const (
 	TAGS            Property = "Tags"
 	INSTANCE_TYPE   Property = "InstanceType"
 	AMI             Property = "AMI"
+	USER_DATA       Property = "UserData"
+	IAM_PROFILE     Property = "IamInstanceProfile"
+	METADATA        Property = "MetadataOptions"
+	POWER_STATE     Property = "PowerState"
 	VOLUME          Property = "Volumes"
 )
 
@@ -0,0 +1,70 @@
+package updater
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+	ot "github.com/crossplane/provider-customcomputeprovider/internal/types"
+)
+
+// IAMInstanceProfileOperation attaches, swaps or detaches the instance
+// profile of the running instance.
+type IAMInstanceProfileOperation struct {
+	BaseOperation
+}
+
+func NewIAMInstanceProfileOperation(logger logging.Logger) *IAMInstanceProfileOperation {
+	return &IAMInstanceProfileOperation{
+		BaseOperation: BaseOperation{opType: ot.IAM_PROFILE.String(), logger: logger},
+	}
+}
+
+func (o *IAMInstanceProfileOperation) Execute(ctx UpdateContext) error {
+	association, err := ctx.Client.GetIamInstanceProfileAssociation(ctx.Context, *ctx.Current.InstanceId)
+	if err != nil {
+		return err
+	}
+
+	desired := ctx.Desired.IAMInstanceProfile
+
+	// EC2 rejects any change while a previous one is still being applied.
+	if association != nil && association.State != types.IamInstanceProfileAssociationStateAssociated {
+		o.logger.Info("instance profile association in progress",
+			"association", *association.AssociationId,
+			"state", association.State)
+		return ErrInProgress
+	}
+
+	switch {
+	case association == nil && desired == "":
+		return nil
+
+	case association == nil:
+		o.logger.Info("associating instance profile", "profile", desired)
+		_, err = ctx.Client.Client.AssociateIamInstanceProfile(ctx.Context, &ec2.AssociateIamInstanceProfileInput{
+			InstanceId:         ctx.Current.InstanceId,
+			IamInstanceProfile: provider.InstanceProfileSpecification(desired),
+		})
+
+	case desired == "":
+		o.logger.Info("disassociating instance profile", "association", *association.AssociationId)
+		_, err = ctx.Client.Client.DisassociateIamInstanceProfile(ctx.Context, &ec2.DisassociateIamInstanceProfileInput{
+			AssociationId: association.AssociationId,
+		})
+
+	case provider.InstanceProfileMatches(association.IamInstanceProfile, desired):
+		return nil
+
+	default:
+		o.logger.Info("replacing instance profile",
+			"association", *association.AssociationId,
+			"profile", desired)
+		_, err = ctx.Client.Client.ReplaceIamInstanceProfileAssociation(ctx.Context, &ec2.ReplaceIamInstanceProfileAssociationInput{
+			AssociationId:      association.AssociationId,
+			IamInstanceProfile: provider.InstanceProfileSpecification(desired),
+		})
+	}
+
+	return err
+}
@@ -0,0 +1,133 @@
+package updater
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider/fake"
+)
+
+// association describes an association of the instance profile in the state.
+func association(arn string, state types.IamInstanceProfileAssociationState) *types.IamInstanceProfileAssociation {
+	return &types.IamInstanceProfileAssociation{
+		AssociationId:      aws.String("iip-assoc-1"),
+		InstanceId:         aws.String("i-1"),
+		IamInstanceProfile: &types.IamInstanceProfile{Arn: aws.String(arn)},
+		State:              state,
+	}
+}
+
+func TestIAMInstanceProfileExecute(t *testing.T) {
+	const webArn = "arn:aws:iam::123456789012:instance-profile/web"
+
+	type want struct {
+		calls   []string
+		profile *types.IamInstanceProfileSpecification
+		err     error
+	}
+
+	cases := map[string]struct {
+		reason      string
+		association *types.IamInstanceProfileAssociation
+		desired     string
+		want        want
+	}{
+		"Associate": {
+			reason:  "A profile should be associated with an instance that has none.",
+			desired: "web",
+			want: want{
+				calls:   []string{"DescribeIamInstanceProfileAssociations", "AssociateIamInstanceProfile"},
+				profile: &types.IamInstanceProfileSpecification{Name: aws.String("web")},
+			},
+		},
+		"AssociateByArn": {
+			reason:  "A profile given by ARN should be associated by ARN.",
+			desired: webArn,
+			want: want{
+				calls:   []string{"DescribeIamInstanceProfileAssociations", "AssociateIamInstanceProfile"},
+				profile: &types.IamInstanceProfileSpecification{Arn: aws.String(webArn)},
+			},
+		},
+		"Replace": {
+			reason:      "A different profile should replace the associated one.",
+			association: association("arn:aws:iam::123456789012:instance-profile/batch", types.IamInstanceProfileAssociationStateAssociated),
+			desired:     "web",
+			want: want{
+				calls:   []string{"DescribeIamInstanceProfileAssociations", "ReplaceIamInstanceProfileAssociation"},
+				profile: &types.IamInstanceProfileSpecification{Name: aws.String("web")},
+			},
+		},
+		"Disassociate": {
+			reason:      "The associated profile should be disassociated when none is desired.",
+			association: association(webArn, types.IamInstanceProfileAssociationStateAssociated),
+			want:        want{calls: []string{"DescribeIamInstanceProfileAssociations", "DisassociateIamInstanceProfile"}},
+		},
+		"UpToDate": {
+			reason:      "The desired profile already associated should be left alone.",
+			association: association(webArn, types.IamInstanceProfileAssociationStateAssociated),
+			desired:     "web",
+			want:        want{calls: []string{"DescribeIamInstanceProfileAssociations"}},
+		},
+		"NoneDesired": {
+			reason: "An instance without a profile should be left alone when none is desired.",
+			want:   want{calls: []string{"DescribeIamInstanceProfileAssociations"}},
+		},
+		"Associating": {
+			reason:      "A change should wait on an association still being applied.",
+			association: association(webArn, types.IamInstanceProfileAssociationStateAssociating),
+			desired:     "batch",
+			want:        want{calls: []string{"DescribeIamInstanceProfileAssociations"}, err: ErrInProgress},
+		},
+		"Disassociating": {
+			reason:      "A change should wait on a disassociation still being applied.",
+			association: association(webArn, types.IamInstanceProfileAssociationStateDisassociating),
+			desired:     "batch",
+			want:        want{calls: []string{"DescribeIamInstanceProfileAssociations"}, err: ErrInProgress},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var profile *types.IamInstanceProfileSpecification
+			describe := &ec2.DescribeIamInstanceProfileAssociationsOutput{}
+			if tc.association != nil {
+				describe.IamInstanceProfileAssociations = []types.IamInstanceProfileAssociation{*tc.association}
+			}
+			fakeEC2 := &fake.EC2{Outputs: map[string]fake.Output{
+				"DescribeIamInstanceProfileAssociations": fake.Answer(describe),
+				"AssociateIamInstanceProfile": func(input interface{}) (interface{}, error) {
+					profile = input.(*ec2.AssociateIamInstanceProfileInput).IamInstanceProfile
+					return &ec2.AssociateIamInstanceProfileOutput{}, nil
+				},
+				"ReplaceIamInstanceProfileAssociation": func(input interface{}) (interface{}, error) {
+					profile = input.(*ec2.ReplaceIamInstanceProfileAssociationInput).IamInstanceProfile
+					return &ec2.ReplaceIamInstanceProfileAssociationOutput{}, nil
+				},
+				"DisassociateIamInstanceProfile": fake.Answer(&ec2.DisassociateIamInstanceProfileOutput{}),
+			}}
+			current := fake.Instance("i-1", types.InstanceStateNameRunning)
+
+			err := NewIAMInstanceProfileOperation(logging.NewNopLogger()).Execute(UpdateContext{
+				Context: context.Background(),
+				Current: &current,
+				Desired: &v1alpha1.InstanceConfig{IAMInstanceProfile: tc.desired},
+				Status:  &v1alpha1.ComputeObservation{InstanceID: "i-1"},
+				Client:  &provider.EC2Client{Client: fakeEC2.Client()},
+			})
+
+			got := want{calls: fakeEC2.Calls, profile: profile, err: err}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), cmpopts.IgnoreUnexported(types.IamInstanceProfileSpecification{}), cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nExecute(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
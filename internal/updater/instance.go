@@ -2,10 +2,13 @@ package updater
 
 import (
 	"context"
+	"fmt"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	ot "github.com/crossplane/provider-customcomputeprovider/internal/types"
 )
 
 type TypeUpdateOperation struct {
@@ -13,28 +16,66 @@ type TypeUpdateOperation struct {
 }
 
 func NewTypeUpdateOperation(logger logging.Logger) *TypeUpdateOperation {
-	return &TypeUpdateOperation{BaseOperation: BaseOperation{opType: "TYPE", logger: logger}}
+	return &TypeUpdateOperation{BaseOperation: BaseOperation{opType: ot.INSTANCE_TYPE.String(), disruptive: true, logger: logger}}
 }
 
-func (u *TypeUpdateOperation) Execute(ctx UpdateContext) error {
-	if err := stopInstance(ctx.Context,
-		ctx.Client.Client,
-		ctx.Current.InstanceId,
-	); err != nil {
-		return err
+// Preflight rejects a type the instance cannot run as, or one EC2 already
+// refused, while the instance is still untouched.
+func (u *TypeUpdateOperation) Preflight(ctx UpdateContext) error {
+	if rejected := ctx.Status.RejectedInstanceType; rejected != "" {
+		if rejected == ctx.Desired.InstanceType {
+			return fmt.Errorf("instance type %s was refused by EC2 and rolled back, change the instance type to try again", rejected)
+		}
+		ctx.Status.RejectedInstanceType = ""
 	}
 
+	return ctx.Client.CheckInstanceTypeCompatibility(ctx.Context, ctx.Current, ctx.Desired.InstanceType)
+}
+
+// Execute changes the instance type. The orchestrator stops the instance
+// before and starts it again after. A refused type is rolled back to the
+// original one so the instance is started as it was.
+func (u *TypeUpdateOperation) Execute(ctx UpdateContext) error {
 	_, err := ctx.Client.Client.ModifyInstanceAttribute(ctx.Context, &ec2.ModifyInstanceAttributeInput{
 		InstanceId: ctx.Current.InstanceId,
 		InstanceType: &types.AttributeValue{
 			Value: &ctx.Desired.InstanceType,
 		},
 	})
+	if err == nil {
+		return nil
+	}
+
+	u.logger.Info("failed to change instance type, rolling back",
+		"instanceType", ctx.Desired.InstanceType,
+		"original", ctx.Current.InstanceType,
+		"error", err)
+
+	if rollbackErr := u.rollback(ctx); rollbackErr != nil {
+		return fmt.Errorf("failed to change instance type to %s: %w, rollback failed: %w", ctx.Desired.InstanceType, err, rollbackErr)
+	}
 
+	ctx.Status.RejectedInstanceType = ctx.Desired.InstanceType
+	return fmt.Errorf("failed to change instance type to %s: %w: %w", ctx.Desired.InstanceType, err, ErrRolledBack)
+}
+
+// rollback restores the type the instance had before the operation.
+func (u *TypeUpdateOperation) rollback(ctx UpdateContext) error {
+	instance, err := ctx.Client.GetInstanceByID(ctx.Context, *ctx.Current.InstanceId)
 	if err != nil {
 		return err
 	}
-	return startInstance(ctx.Context, ctx.Client.Client, ctx.Current.InstanceId)
+	if instance.InstanceType == ctx.Current.InstanceType {
+		return nil
+	}
+
+	_, err = ctx.Client.Client.ModifyInstanceAttribute(ctx.Context, &ec2.ModifyInstanceAttributeInput{
+		InstanceId: ctx.Current.InstanceId,
+		InstanceType: &types.AttributeValue{
+			Value: aws.String(string(ctx.Current.InstanceType)),
+		},
+	})
+	return err
 }
 
 func startInstance(ctx context.Context, c *ec2.Client, instanceId *string) error {
@@ -0,0 +1,215 @@
+package updater
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider/fake"
+	ot "github.com/crossplane/provider-customcomputeprovider/internal/types"
+)
+
+func TestTypeUpdatePreflight(t *testing.T) {
+	type want struct {
+		err      bool
+		rejected string
+		pending  bool
+		calls    []string
+	}
+
+	cases := map[string]struct {
+		reason        string
+		rejected      string
+		instanceTypes func(interface{}) (interface{}, error)
+		want          want
+	}{
+		"Compatible": {
+			reason:        "A type the instance can run as should start the stop window.",
+			instanceTypes: fake.Answer(&ec2.DescribeInstanceTypesOutput{InstanceTypes: []types.InstanceTypeInfo{{}}}),
+			want: want{
+				pending: true,
+				calls:   []string{"DescribeInstanceTypes", "DescribeImages", "StopInstances"},
+			},
+		},
+		"Refused": {
+			reason:   "A type EC2 already refused should not stop the instance again.",
+			rejected: "m5.xlarge",
+			want:     want{err: true, rejected: "m5.xlarge"},
+		},
+		"RefusedOtherType": {
+			reason:        "A change to another type than the refused one should be tried.",
+			rejected:      "m5.2xlarge",
+			instanceTypes: fake.Answer(&ec2.DescribeInstanceTypesOutput{InstanceTypes: []types.InstanceTypeInfo{{}}}),
+			want: want{
+				pending: true,
+				calls:   []string{"DescribeInstanceTypes", "DescribeImages", "StopInstances"},
+			},
+		},
+		"UnknownType": {
+			reason: "A type that does not exist should not stop the instance.",
+			instanceTypes: func(interface{}) (interface{}, error) {
+				return nil, &smithy.GenericAPIError{Code: "InvalidInstanceType"}
+			},
+			want: want{err: true, calls: []string{"DescribeInstanceTypes"}},
+		},
+		"IncompatibleArchitecture": {
+			reason: "A type that does not support the architecture of the image should not stop the instance.",
+			instanceTypes: fake.Answer(&ec2.DescribeInstanceTypesOutput{InstanceTypes: []types.InstanceTypeInfo{{
+				ProcessorInfo: &types.ProcessorInfo{SupportedArchitectures: []types.ArchitectureType{types.ArchitectureTypeArm64}},
+			}}}),
+			want: want{err: true, calls: []string{"DescribeInstanceTypes", "DescribeImages"}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fakeEC2 := &fake.EC2{Outputs: map[string]fake.Output{
+				"DescribeInstanceTypes": tc.instanceTypes,
+				"DescribeImages": fake.Answer(&ec2.DescribeImagesOutput{Images: []types.Image{{
+					Architecture: types.ArchitectureValuesX8664,
+				}}}),
+				"StopInstances": fake.Answer(&ec2.StopInstancesOutput{}),
+			}}
+			current := fake.Instance("i-1", types.InstanceStateNameRunning)
+			status := &v1alpha1.ComputeObservation{InstanceID: "i-1", RejectedInstanceType: tc.rejected}
+
+			err := NewUpdateOrchestrator(logging.NewNopLogger()).ExecuteUpdates(UpdateContext{
+				Context: context.Background(),
+				Current: &current,
+				Desired: &v1alpha1.InstanceConfig{InstanceType: "m5.xlarge"},
+				Status:  status,
+				Client:  &provider.EC2Client{Client: fakeEC2.Client()},
+				Logger:  logging.NewNopLogger(),
+			}, map[string]bool{ot.INSTANCE_TYPE.String(): true})
+
+			got := want{
+				err:      err != nil,
+				rejected: status.RejectedInstanceType,
+				pending:  status.PendingOperation != nil,
+				calls:    fakeEC2.Calls,
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nExecuteUpdates(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestTypeUpdateRollback(t *testing.T) {
+	refused := &smithy.GenericAPIError{Code: "InsufficientInstanceCapacity"}
+
+	type want struct {
+		rolledBack bool
+		rejected   string
+		phase      string
+		step       int
+		calls      []string
+	}
+
+	cases := map[string]struct {
+		reason    string
+		step      int
+		described types.InstanceType
+		modify    []error
+		want      want
+	}{
+		"TypeUnchanged": {
+			reason:    "A refused type that left the instance as it was should be recorded and skipped.",
+			described: types.InstanceTypeM5Large,
+			modify:    []error{refused},
+			want: want{
+				rolledBack: true,
+				rejected:   "m5.xlarge",
+				phase:      phaseApplying,
+				step:       1,
+				calls:      []string{"ModifyInstanceAttribute", "DescribeInstances"},
+			},
+		},
+		"TypeChanged": {
+			reason:    "A refused type that changed the instance should be changed back to the original type.",
+			described: types.InstanceTypeM5Xlarge,
+			modify:    []error{refused, nil},
+			want: want{
+				rolledBack: true,
+				rejected:   "m5.xlarge",
+				phase:      phaseApplying,
+				step:       1,
+				calls:      []string{"ModifyInstanceAttribute", "DescribeInstances", "ModifyInstanceAttribute"},
+			},
+		},
+		"RollbackFailed": {
+			reason:    "A rollback that failed should be retried rather than skipped.",
+			described: types.InstanceTypeM5Xlarge,
+			modify:    []error{refused, errors.New("boom")},
+			want: want{
+				phase: phaseApplying,
+				calls: []string{"ModifyInstanceAttribute", "DescribeInstances", "ModifyInstanceAttribute"},
+			},
+		},
+		"ResumedAfterRollback": {
+			reason:    "The instance should be started again once the rolled back operation is skipped.",
+			step:      1,
+			described: types.InstanceTypeM5Large,
+			want: want{
+				phase: phaseStarting,
+				step:  1,
+				calls: []string{"DescribeInstances", "StartInstances"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			described := fake.Instance("i-1", types.InstanceStateNameStopped)
+			described.InstanceType = tc.described
+
+			modified := 0
+			fakeEC2 := &fake.EC2{Outputs: map[string]fake.Output{
+				"DescribeInstances": fake.DescribeInstances(described),
+				"StartInstances":    fake.Answer(&ec2.StartInstancesOutput{}),
+				"ModifyInstanceAttribute": func(interface{}) (interface{}, error) {
+					err := tc.modify[modified]
+					modified++
+					return &ec2.ModifyInstanceAttributeOutput{}, err
+				},
+			}}
+			current := fake.Instance("i-1", types.InstanceStateNameStopped)
+			status := &v1alpha1.ComputeObservation{
+				InstanceID: "i-1",
+				PendingOperation: &v1alpha1.PendingOperation{
+					Operations: []string{ot.INSTANCE_TYPE.String()},
+					Phase:      phaseApplying,
+					Step:       tc.step,
+				},
+			}
+
+			err := NewUpdateOrchestrator(logging.NewNopLogger()).advance(UpdateContext{
+				Context: context.Background(),
+				Current: &current,
+				Desired: &v1alpha1.InstanceConfig{InstanceType: "m5.xlarge"},
+				Status:  status,
+				Client:  &provider.EC2Client{Client: fakeEC2.Client()},
+				Logger:  logging.NewNopLogger(),
+			})
+
+			got := want{
+				rolledBack: errors.Is(err, ErrRolledBack),
+				rejected:   status.RejectedInstanceType,
+				phase:      status.PendingOperation.Phase,
+				step:       status.PendingOperation.Step,
+				calls:      fakeEC2.Calls,
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nadvance(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
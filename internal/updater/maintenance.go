@@ -0,0 +1,126 @@
+package updater
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	apisv1alpha1 "github.com/crossplane/provider-customcomputeprovider/apis/v1alpha1"
+)
+
+// maintenanceWindow reports whether t falls within the window and, when it
+// does not, when the window opens next. Without a window updates run at any
+// time.
+func maintenanceWindow(w *apisv1alpha1.MaintenanceWindow, t time.Time) (bool, time.Time, error) {
+	if w == nil || len(w.Ranges) == 0 {
+		return true, time.Time{}, nil
+	}
+
+	tz := w.Timezone
+	if tz == "" {
+		tz = "UTC"
+	}
+	location, err := time.LoadLocation(tz)
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("invalid maintenance window timezone %q: %w", tz, err)
+	}
+	t = t.In(location)
+
+	var next time.Time
+	for _, r := range w.Ranges {
+		start, err := time.Parse("15:04", r.Start)
+		if err != nil {
+			return false, time.Time{}, fmt.Errorf("invalid maintenance window start %q: %w", r.Start, err)
+		}
+		end, err := time.Parse("15:04", r.End)
+		if err != nil {
+			return false, time.Time{}, fmt.Errorf("invalid maintenance window end %q: %w", r.End, err)
+		}
+
+		// A range starting the day before may still be open, the next one
+		// starts within a week.
+		for offset := -1; offset <= 7; offset++ {
+			day := time.Date(t.Year(), t.Month(), t.Day()+offset, 0, 0, 0, 0, location)
+			if !onDay(r.Days, day.Weekday()) {
+				continue
+			}
+
+			opens := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, location)
+			closes := time.Date(day.Year(), day.Month(), day.Day(), end.Hour(), end.Minute(), 0, 0, location)
+			if !closes.After(opens) {
+				closes = closes.AddDate(0, 0, 1)
+			}
+
+			if !t.Before(opens) && t.Before(closes) {
+				return true, time.Time{}, nil
+			}
+			if opens.After(t) && (next.IsZero() || opens.Before(next)) {
+				next = opens
+			}
+		}
+	}
+
+	return false, next, nil
+}
+
+// ValidateMaintenanceWindow reports why the ranges or the timezone of the
+// window cannot be evaluated.
+func ValidateMaintenanceWindow(w *apisv1alpha1.MaintenanceWindow) error {
+	if w == nil {
+		return nil
+	}
+
+	if w.Timezone != "" {
+		if _, err := time.LoadLocation(w.Timezone); err != nil {
+			return fmt.Errorf("invalid maintenance window timezone %q: %w", w.Timezone, err)
+		}
+	}
+
+	for _, r := range w.Ranges {
+		if _, err := time.Parse("15:04", r.Start); err != nil {
+			return fmt.Errorf("invalid maintenance window start %q: %w", r.Start, err)
+		}
+		if _, err := time.Parse("15:04", r.End); err != nil {
+			return fmt.Errorf("invalid maintenance window end %q: %w", r.End, err)
+		}
+	}
+
+	return nil
+}
+
+// maintenanceCheck evaluates the maintenance window at most once per update,
+// the first time an operation interrupting the instance asks for it. An
+// invalid window never opens, the operations wait until it is fixed.
+type maintenanceCheck struct {
+	window *apisv1alpha1.MaintenanceWindow
+	logger logging.Logger
+
+	done bool
+	open bool
+	next time.Time
+}
+
+func (m *maintenanceCheck) evaluate() (bool, time.Time) {
+	if !m.done {
+		var err error
+		m.open, m.next, err = maintenanceWindow(m.window, time.Now())
+		if err != nil {
+			m.logger.Info("holding disruptive operations on an invalid maintenance window", "error", err)
+			m.open, m.next = false, time.Time{}
+		}
+		m.done = true
+	}
+	return m.open, m.next
+}
+
+func onDay(days []apisv1alpha1.Weekday, weekday time.Weekday) bool {
+	if len(days) == 0 {
+		return true
+	}
+	for _, d := range days {
+		if string(d) == weekday.String() {
+			return true
+		}
+	}
+	return false
+}
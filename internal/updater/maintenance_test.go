@@ -0,0 +1,138 @@
+package updater
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	apisv1alpha1 "github.com/crossplane/provider-customcomputeprovider/apis/v1alpha1"
+)
+
+func TestMaintenanceWindow(t *testing.T) {
+	weekendNights := &apisv1alpha1.MaintenanceWindow{
+		Ranges: []apisv1alpha1.MaintenanceRange{
+			{Days: []apisv1alpha1.Weekday{"Saturday", "Sunday"}, Start: "22:00", End: "04:00"},
+		},
+	}
+
+	type want struct {
+		open bool
+		next time.Time
+	}
+
+	cases := map[string]struct {
+		reason string
+		window *apisv1alpha1.MaintenanceWindow
+		now    time.Time
+		want   want
+	}{
+		"NoWindow": {
+			reason: "Updates should run at any time without a maintenance window.",
+			now:    time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC),
+			want:   want{open: true},
+		},
+		"Outside": {
+			reason: "A time outside of the ranges should wait on the next one.",
+			window: weekendNights,
+			now:    time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC),
+			want:   want{open: false, next: time.Date(2024, 3, 9, 22, 0, 0, 0, time.UTC)},
+		},
+		"Within": {
+			reason: "A time within a range should be open.",
+			window: weekendNights,
+			now:    time.Date(2024, 3, 9, 23, 0, 0, 0, time.UTC),
+			want:   want{open: true},
+		},
+		"AfterMidnight": {
+			reason: "A range spanning midnight should stay open on the next day.",
+			window: weekendNights,
+			now:    time.Date(2024, 3, 11, 3, 0, 0, 0, time.UTC),
+			want:   want{open: true},
+		},
+		"Closed": {
+			reason: "The end of a range should be closed.",
+			window: weekendNights,
+			now:    time.Date(2024, 3, 11, 4, 0, 0, 0, time.UTC),
+			want:   want{open: false, next: time.Date(2024, 3, 16, 22, 0, 0, 0, time.UTC)},
+		},
+		"Timezone": {
+			reason: "Ranges should be evaluated in the timezone of the window.",
+			window: &apisv1alpha1.MaintenanceWindow{
+				Timezone: "America/New_York",
+				Ranges:   []apisv1alpha1.MaintenanceRange{{Start: "02:00", End: "03:00"}},
+			},
+			now:  time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC),
+			want: want{open: false, next: time.Date(2024, 3, 6, 7, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			open, next, err := maintenanceWindow(tc.window, tc.now)
+			if err != nil {
+				t.Fatalf("maintenanceWindow(...): %v", err)
+			}
+
+			got := want{open: open}
+			if !next.IsZero() {
+				got.next = next.UTC()
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nmaintenanceWindow(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestValidateMaintenanceWindow(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		window *apisv1alpha1.MaintenanceWindow
+		valid  bool
+	}{
+		"NoWindow": {
+			reason: "A missing window should be valid.",
+			valid:  true,
+		},
+		"Valid": {
+			reason: "A window with parseable ranges and timezone should be valid.",
+			window: &apisv1alpha1.MaintenanceWindow{
+				Timezone: "Europe/Paris",
+				Ranges:   []apisv1alpha1.MaintenanceRange{{Start: "22:00", End: "04:00"}},
+			},
+			valid: true,
+		},
+		"InvalidTimezone": {
+			reason: "An unknown timezone should be invalid.",
+			window: &apisv1alpha1.MaintenanceWindow{
+				Timezone: "Mars/Olympus_Mons",
+				Ranges:   []apisv1alpha1.MaintenanceRange{{Start: "22:00", End: "04:00"}},
+			},
+		},
+		"InvalidStart": {
+			reason: "A start out of the day should be invalid.",
+			window: &apisv1alpha1.MaintenanceWindow{
+				Ranges: []apisv1alpha1.MaintenanceRange{{Start: "25:00", End: "04:00"}},
+			},
+		},
+		"InvalidEnd": {
+			reason: "An end out of the hour should be invalid.",
+			window: &apisv1alpha1.MaintenanceWindow{
+				Ranges: []apisv1alpha1.MaintenanceRange{{Start: "22:00", End: "04:60"}},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateMaintenanceWindow(tc.window)
+			if got := err == nil; got != tc.valid {
+				t.Errorf("\n%s\nValidateMaintenanceWindow(...): want valid %t, got error %v\n", tc.reason, tc.valid, err)
+			}
+			if _, _, werr := maintenanceWindow(tc.window, time.Now()); (werr == nil) != tc.valid {
+				t.Errorf("\n%s\nmaintenanceWindow(...) disagrees with ValidateMaintenanceWindow(...): %v\n", tc.reason, werr)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package updater
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+	ot "github.com/crossplane/provider-customcomputeprovider/internal/types"
+)
+
+// MetadataOptionsOperation enforces the metadata options on the running
+// instance.
+type MetadataOptionsOperation struct {
+	BaseOperation
+}
+
+func NewMetadataOptionsOperation(logger logging.Logger) *MetadataOptionsOperation {
+	return &MetadataOptionsOperation{
+		BaseOperation: BaseOperation{opType: ot.METADATA.String(), logger: logger},
+	}
+}
+
+func (o *MetadataOptionsOperation) Execute(ctx UpdateContext) error {
+	if ctx.Desired.MetadataOptions == nil {
+		return nil
+	}
+
+	if current := ctx.Current.MetadataOptions; current != nil && current.State == types.InstanceMetadataOptionsStatePending {
+		o.logger.Info("metadata options change in progress")
+		return ErrInProgress
+	}
+
+	request := provider.MetadataOptionsRequest(ctx.Desired.MetadataOptions)
+	_, err := ctx.Client.Client.ModifyInstanceMetadataOptions(ctx.Context, &ec2.ModifyInstanceMetadataOptionsInput{
+		InstanceId:              ctx.Current.InstanceId,
+		HttpTokens:              request.HttpTokens,
+		HttpPutResponseHopLimit: request.HttpPutResponseHopLimit,
+		HttpEndpoint:            request.HttpEndpoint,
+		InstanceMetadataTags:    request.InstanceMetadataTags,
+	})
+
+	return err
+}
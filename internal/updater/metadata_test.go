@@ -0,0 +1,78 @@
+package updater
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider/fake"
+)
+
+func TestMetadataOptionsExecute(t *testing.T) {
+	type want struct {
+		calls      []string
+		httpTokens types.HttpTokensState
+		err        error
+	}
+
+	cases := map[string]struct {
+		reason  string
+		state   types.InstanceMetadataOptionsState
+		desired *v1alpha1.MetadataOptions
+		want    want
+	}{
+		"Unmanaged": {
+			reason: "Metadata options the Compute does not configure should be left alone.",
+			state:  types.InstanceMetadataOptionsStateApplied,
+		},
+		"Applied": {
+			reason:  "The desired options should be sent once the previous change is applied.",
+			state:   types.InstanceMetadataOptionsStateApplied,
+			desired: &v1alpha1.MetadataOptions{HTTPTokens: "required"},
+			want:    want{calls: []string{"ModifyInstanceMetadataOptions"}, httpTokens: types.HttpTokensStateRequired},
+		},
+		"Pending": {
+			reason:  "A change should wait on the previous one while it is pending.",
+			state:   types.InstanceMetadataOptionsStatePending,
+			desired: &v1alpha1.MetadataOptions{HTTPTokens: "required"},
+			want:    want{err: ErrInProgress},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var httpTokens types.HttpTokensState
+			fakeEC2 := &fake.EC2{Outputs: map[string]fake.Output{
+				"ModifyInstanceMetadataOptions": func(input interface{}) (interface{}, error) {
+					httpTokens = input.(*ec2.ModifyInstanceMetadataOptionsInput).HttpTokens
+					return &ec2.ModifyInstanceMetadataOptionsOutput{}, nil
+				},
+			}}
+			current := fake.Instance("i-1", types.InstanceStateNameRunning)
+			current.MetadataOptions = &types.InstanceMetadataOptionsResponse{
+				HttpTokens: types.HttpTokensStateOptional,
+				State:      tc.state,
+			}
+
+			err := NewMetadataOptionsOperation(logging.NewNopLogger()).Execute(UpdateContext{
+				Context: context.Background(),
+				Current: &current,
+				Desired: &v1alpha1.InstanceConfig{MetadataOptions: tc.desired},
+				Status:  &v1alpha1.ComputeObservation{InstanceID: "i-1"},
+				Client:  &provider.EC2Client{Client: fakeEC2.Client()},
+			})
+
+			got := want{calls: fakeEC2.Calls, httpTokens: httpTokens, err: err}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nExecute(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
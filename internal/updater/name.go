@@ -5,6 +5,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	ot "github.com/crossplane/provider-customcomputeprovider/internal/types"
 )
 
 type NameUpdateOperation struct {
@@ -13,7 +14,7 @@ type NameUpdateOperation struct {
 
 func NewNameOperation(logger logging.Logger) *NameUpdateOperation {
 	return &NameUpdateOperation{
-		BaseOperation: BaseOperation{opType: "NAME", logger: logger},
+		BaseOperation: BaseOperation{opType: ot.NAME.String(), logger: logger},
 	}
 }
 
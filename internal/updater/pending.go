@@ -0,0 +1,153 @@
+package updater
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	phaseStopping = "Stopping"
+	phaseApplying = "Applying"
+	phaseStarting = "Starting"
+)
+
+// ErrInProgress is returned by an operation that is waiting on EC2. It is
+// executed again on the next reconcile, with the progress kept in the
+// pending operation.
+var ErrInProgress = errors.New("operation in progress")
+
+// ErrRolledBack is returned by an operation that failed while the instance was
+// stopped and undid its change. The pending operation carries on with the
+// remaining operations and starts the instance again.
+var ErrRolledBack = errors.New("operation rolled back")
+
+func newPendingOperation(operations ...string) *v1alpha1.PendingOperation {
+	return &v1alpha1.PendingOperation{
+		Operations: operations,
+		Phase:      phaseStopping,
+		StartedAt:  metav1.Now(),
+	}
+}
+
+// newOnlineOperation tracks an operation applied while the instance keeps
+// running, it starts in the applying phase and leaves the instance as is.
+func newOnlineOperation(operations ...string) *v1alpha1.PendingOperation {
+	return &v1alpha1.PendingOperation{
+		Operations: operations,
+		Phase:      phaseApplying,
+		StartedAt:  metav1.Now(),
+	}
+}
+
+// advance moves the pending operation forward as far as EC2 allows without
+// waiting: it stops the instance, applies the operations one step at a time
+// and starts the instance again. It returns as soon as a step has to wait,
+// the next reconcile resumes from the phase recorded in the status.
+func (o *UpdateOrchestrator) advance(ctx UpdateContext) error {
+	p := ctx.Status.PendingOperation
+
+	for {
+		o.logger.Info("advancing pending operation",
+			"operations", p.Operations,
+			"phase", p.Phase,
+			"step", p.Step)
+
+		switch p.Phase {
+		case phaseStopping:
+			done, err := stopStep(ctx)
+			if err != nil || !done {
+				return err
+			}
+			p.Phase = phaseApplying
+
+		case phaseApplying:
+			for p.Step < len(p.Operations) {
+				op, exists := o.operations[p.Operations[p.Step]]
+				if !exists {
+					return fmt.Errorf("no operation registered for type %s", p.Operations[p.Step])
+				}
+
+				err := op.Execute(ctx)
+				if errors.Is(err, ErrInProgress) {
+					return nil
+				}
+				if errors.Is(err, ErrRolledBack) {
+					o.logger.Info("operation rolled back, resuming the pending operation on next reconcile",
+						"type", p.Operations[p.Step],
+						"error", err)
+					p.Step++
+					return err
+				}
+				if err != nil {
+					return err
+				}
+				p.Step++
+			}
+			p.Phase = phaseStarting
+
+		case phaseStarting:
+			done, err := startStep(ctx)
+			if err != nil || !done {
+				return err
+			}
+
+			o.logger.Info("pending operation completed", "operations", p.Operations)
+			ctx.Status.PendingOperation = nil
+			return nil
+
+		default:
+			return fmt.Errorf("unknown pending operation phase %q", p.Phase)
+		}
+	}
+}
+
+// stopStep reports whether the instance is stopped, requesting the stop
+// first when it is running. An instance meant to be hibernated is hibernated
+// rather than stopped, it is left that way once the operations are applied.
+// An instance on its way out never stops, waiting on it would block the
+// pending operation forever.
+func stopStep(ctx UpdateContext) (bool, error) {
+	switch ctx.Current.State.Name {
+	case types.InstanceStateNameStopped:
+		return true, nil
+	case types.InstanceStateNameShuttingDown, types.InstanceStateNameTerminated:
+		return false, fmt.Errorf("instance %s is %s, it cannot be stopped", *ctx.Current.InstanceId, ctx.Current.State.Name)
+	case types.InstanceStateNameRunning:
+		_, err := ctx.Client.Client.StopInstances(ctx.Context, &ec2.StopInstancesInput{
+			InstanceIds: []string{*ctx.Current.InstanceId},
+			Hibernate:   aws.Bool(provider.DesiredPowerState(ctx.Desired) == v1alpha1.PowerStateHibernated),
+		})
+		return false, err
+	}
+
+	return false, nil
+}
+
+// startStep reports whether the instance is in the desired power state,
+// starting it only when it is meant to run.
+func startStep(ctx UpdateContext) (bool, error) {
+	if provider.DesiredPowerState(ctx.Desired) != v1alpha1.PowerStateRunning {
+		return true, nil
+	}
+
+	instance, err := ctx.Client.GetInstanceByID(ctx.Context, *ctx.Current.InstanceId)
+	if err != nil {
+		return false, err
+	}
+
+	switch instance.State.Name {
+	case types.InstanceStateNameRunning:
+		return true, nil
+	case types.InstanceStateNameStopped:
+		return false, startInstance(ctx.Context, ctx.Client.Client, instance.InstanceId)
+	}
+
+	return false, nil
+}
@@ -0,0 +1,212 @@
+package updater
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider/fake"
+	ot "github.com/crossplane/provider-customcomputeprovider/internal/types"
+)
+
+// stopWindowEC2 answers the calls of a stop window on instance i-1, described
+// in the given state.
+func stopWindowEC2(state types.InstanceStateName) *fake.EC2 {
+	return &fake.EC2{Outputs: map[string]fake.Output{
+		"DescribeInstances":       fake.DescribeInstances(fake.Instance("i-1", state)),
+		"StopInstances":           fake.Answer(&ec2.StopInstancesOutput{}),
+		"StartInstances":          fake.Answer(&ec2.StartInstancesOutput{}),
+		"ModifyInstanceAttribute": fake.Answer(&ec2.ModifyInstanceAttributeOutput{}),
+		"CreateTags":              fake.Answer(&ec2.CreateTagsOutput{}),
+	}}
+}
+
+func TestAdvance(t *testing.T) {
+	instanceTypeOnly := []string{ot.INSTANCE_TYPE.String()}
+	userDataAndType := []string{ot.USER_DATA.String(), ot.INSTANCE_TYPE.String()}
+
+	type want struct {
+		phase string
+		step  int
+		calls []string
+	}
+
+	cases := map[string]struct {
+		reason  string
+		pending *v1alpha1.PendingOperation
+		state   types.InstanceStateName
+		power   v1alpha1.PowerState
+		want    want
+	}{
+		"StoppingRunning": {
+			reason:  "A running instance should be asked to stop, the operations wait on it.",
+			pending: &v1alpha1.PendingOperation{Operations: instanceTypeOnly, Phase: phaseStopping},
+			state:   types.InstanceStateNameRunning,
+			want:    want{phase: phaseStopping, calls: []string{"StopInstances"}},
+		},
+		"StoppingInProgress": {
+			reason:  "A stopping instance should be waited on without asking again.",
+			pending: &v1alpha1.PendingOperation{Operations: instanceTypeOnly, Phase: phaseStopping},
+			state:   types.InstanceStateNameStopping,
+			want:    want{phase: phaseStopping},
+		},
+		"StoppingStopped": {
+			reason:  "A stopped instance should have the operations applied and be started again.",
+			pending: &v1alpha1.PendingOperation{Operations: instanceTypeOnly, Phase: phaseStopping},
+			state:   types.InstanceStateNameStopped,
+			want: want{
+				phase: phaseStarting,
+				step:  1,
+				calls: []string{"ModifyInstanceAttribute", "DescribeInstances", "StartInstances"},
+			},
+		},
+		"ApplyingResumed": {
+			reason:  "Applying should resume at the recorded step rather than apply the done operations again.",
+			pending: &v1alpha1.PendingOperation{Operations: userDataAndType, Phase: phaseApplying, Step: 1},
+			state:   types.InstanceStateNameStopped,
+			want: want{
+				phase: phaseStarting,
+				step:  2,
+				calls: []string{"ModifyInstanceAttribute", "DescribeInstances", "StartInstances"},
+			},
+		},
+		"StartingInProgress": {
+			reason:  "A starting instance should be waited on without asking again.",
+			pending: &v1alpha1.PendingOperation{Operations: instanceTypeOnly, Phase: phaseStarting, Step: 1},
+			state:   types.InstanceStateNamePending,
+			want:    want{phase: phaseStarting, step: 1, calls: []string{"DescribeInstances"}},
+		},
+		"StartingRunning": {
+			reason:  "The pending operation should complete once the instance runs again.",
+			pending: &v1alpha1.PendingOperation{Operations: instanceTypeOnly, Phase: phaseStarting, Step: 1},
+			state:   types.InstanceStateNameRunning,
+			want:    want{calls: []string{"DescribeInstances"}},
+		},
+		"StartingKeptStopped": {
+			reason:  "An instance meant to stay stopped should not be started again.",
+			pending: &v1alpha1.PendingOperation{Operations: instanceTypeOnly, Phase: phaseStarting, Step: 1},
+			state:   types.InstanceStateNameStopped,
+			power:   v1alpha1.PowerStateStopped,
+			want:    want{},
+		},
+		"Online": {
+			reason:  "An online operation should be applied without stopping or starting the instance.",
+			pending: newOnlineOperation(ot.TAGS.String()),
+			state:   types.InstanceStateNameRunning,
+			want:    want{calls: []string{"CreateTags", "DescribeInstances"}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fakeEC2 := stopWindowEC2(tc.state)
+			current := fake.Instance("i-1", tc.state)
+			status := &v1alpha1.ComputeObservation{InstanceID: "i-1", PendingOperation: tc.pending}
+
+			o := NewUpdateOrchestrator(logging.NewNopLogger())
+			err := o.advance(UpdateContext{
+				Context: context.Background(),
+				Current: &current,
+				Desired: &v1alpha1.InstanceConfig{
+					InstanceType: "m5.xlarge",
+					UserData:     "#!/bin/sh",
+					InstanceTags: map[string]string{"team": "infra"},
+					PowerState:   tc.power,
+				},
+				Status: status,
+				Client: &provider.EC2Client{Client: fakeEC2.Client()},
+				Logger: logging.NewNopLogger(),
+			})
+			if err != nil {
+				t.Fatalf("advance(...): %v", err)
+			}
+
+			got := want{calls: fakeEC2.Calls}
+			if p := status.PendingOperation; p != nil {
+				got.phase, got.step = p.Phase, p.Step
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nadvance(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestStopStep(t *testing.T) {
+	type want struct {
+		stopped   bool
+		hibernate *bool
+		calls     []string
+		err       bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		state  types.InstanceStateName
+		power  v1alpha1.PowerState
+		want   want
+	}{
+		"Running": {
+			reason: "A running instance should be asked to stop.",
+			state:  types.InstanceStateNameRunning,
+			want:   want{hibernate: aws.Bool(false), calls: []string{"StopInstances"}},
+		},
+		"RunningHibernated": {
+			reason: "A running instance meant to be hibernated should be hibernated rather than stopped.",
+			state:  types.InstanceStateNameRunning,
+			power:  v1alpha1.PowerStateHibernated,
+			want:   want{hibernate: aws.Bool(true), calls: []string{"StopInstances"}},
+		},
+		"Stopping": {
+			reason: "A stopping instance should be waited on.",
+			state:  types.InstanceStateNameStopping,
+		},
+		"Stopped": {
+			reason: "A stopped instance should be reported stopped.",
+			state:  types.InstanceStateNameStopped,
+			want:   want{stopped: true},
+		},
+		"ShuttingDown": {
+			reason: "An instance shutting down never stops and should not be waited on.",
+			state:  types.InstanceStateNameShuttingDown,
+			want:   want{err: true},
+		},
+		"Terminated": {
+			reason: "A terminated instance never stops and should not be waited on.",
+			state:  types.InstanceStateNameTerminated,
+			want:   want{err: true},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var hibernate *bool
+			fakeEC2 := &fake.EC2{Outputs: map[string]fake.Output{
+				"StopInstances": func(input interface{}) (interface{}, error) {
+					hibernate = input.(*ec2.StopInstancesInput).Hibernate
+					return &ec2.StopInstancesOutput{}, nil
+				},
+			}}
+			current := fake.Instance("i-1", tc.state)
+
+			stopped, err := stopStep(UpdateContext{
+				Context: context.Background(),
+				Current: &current,
+				Desired: &v1alpha1.InstanceConfig{PowerState: tc.power},
+				Client:  &provider.EC2Client{Client: fakeEC2.Client()},
+			})
+
+			got := want{stopped: stopped, hibernate: hibernate, calls: fakeEC2.Calls, err: err != nil}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nstopStep(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
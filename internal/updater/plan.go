@@ -0,0 +1,211 @@
+package updater
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+	ot "github.com/crossplane/provider-customcomputeprovider/internal/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// disruptionRank orders the disruptions from the least to the most severe.
+var disruptionRank = map[v1alpha1.Disruption]int{
+	v1alpha1.DisruptionNone:    0,
+	v1alpha1.DisruptionRestart: 1,
+	v1alpha1.DisruptionStop:    2,
+	v1alpha1.DisruptionReplace: 3,
+}
+
+// Plan describes the updates ExecuteUpdates would apply, in execution order,
+// with how each of them affects the instance.
+func (o *UpdateOrchestrator) Plan(ctx UpdateContext, updates map[string]bool) *v1alpha1.UpdatePlan {
+	plan := &v1alpha1.UpdatePlan{Disruption: v1alpha1.DisruptionNone}
+
+	for _, opType := range executionOrder {
+		op, exists := o.operations[opType]
+		if !updates[opType] || !exists {
+			continue
+		}
+
+		current, desired := o.describe(ctx, opType)
+		planned := v1alpha1.PlannedOperation{
+			Type:       opType,
+			Disruption: disruption(ctx, op),
+			Current:    current,
+			Desired:    desired,
+		}
+		if disruptionRank[planned.Disruption] > disruptionRank[plan.Disruption] {
+			plan.Disruption = planned.Disruption
+		}
+		plan.Operations = append(plan.Operations, planned)
+	}
+
+	return plan
+}
+
+// PlanHash identifies the plan created at the given time. Including the time
+// keeps an approval from applying to the same updates found again later.
+func PlanHash(plan *v1alpha1.UpdatePlan, createdAt metav1.Time) string {
+	content, _ := json.Marshal(planChanges(plan))
+	sum := sha256.Sum256(append(content, []byte(createdAt.UTC().String())...))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// SamePlan reports whether both plans make the same changes, so an approval
+// of one applies to the other.
+func SamePlan(a, b *v1alpha1.UpdatePlan) bool {
+	return reflect.DeepEqual(planChanges(a), planChanges(b))
+}
+
+// planChanges lists what the operations of the plan change the instance to.
+// The current values are left out, they follow the instance, e.g. its state
+// while it starts, without changing what the plan applies.
+func planChanges(plan *v1alpha1.UpdatePlan) []string {
+	changes := make([]string, 0, len(plan.Operations))
+	for _, op := range plan.Operations {
+		changes = append(changes, op.Type+"="+op.Desired)
+	}
+	return changes
+}
+
+// disruption classifies how applying the operation affects the instance.
+func disruption(ctx UpdateContext, op Updater) v1alpha1.Disruption {
+	if r, ok := op.(replacer); ok && r.ReplacesInstance(ctx) {
+		return v1alpha1.DisruptionReplace
+	}
+
+	if op.IsDisruptive(ctx) {
+		return v1alpha1.DisruptionRestart
+	}
+
+	if _, ok := op.(*PowerStateOperation); ok && provider.DesiredPowerState(ctx.Desired) != v1alpha1.PowerStateRunning {
+		return v1alpha1.DisruptionStop
+	}
+
+	return v1alpha1.DisruptionNone
+}
+
+// describe returns the current and desired values reconciled by the operation.
+func (o *UpdateOrchestrator) describe(ctx UpdateContext, opType string) (string, string) {
+	current, desired := ctx.Current, ctx.Desired
+
+	switch opType {
+	case ot.AMI.String():
+		return aws.ToString(current.ImageId), desired.InstanceAMI
+
+	case ot.NAME.String():
+		return currentTags(ctx)["Name"], desired.InstanceName
+
+	case ot.TAGS.String():
+		tags := currentTags(ctx)
+		delete(tags, "Name")
+		delete(tags, provider.OwnerTagKey)
+		return formatMap(tags), formatMap(desired.InstanceTags)
+
+	case ot.SECURITY_GROUPS.String():
+		var groups []string
+		for _, g := range current.SecurityGroups {
+			groups = append(groups, aws.ToString(g.GroupId))
+		}
+		return strings.Join(groups, ", "), strings.Join(desired.Networking.InstanceSecurityGroups, ", ")
+
+	case ot.IAM_PROFILE.String():
+		profile := ""
+		if current.IamInstanceProfile != nil {
+			profile = aws.ToString(current.IamInstanceProfile.Arn)
+		}
+		return profile, desired.IAMInstanceProfile
+
+	case ot.METADATA.String():
+		return formatCurrentMetadata(ctx), formatDesiredMetadata(desired.MetadataOptions)
+
+	case ot.USER_DATA.String():
+		// The user data may hold secrets, only its digest is shown.
+		sum := sha256.Sum256([]byte(desired.UserData))
+		return "", "sha256:" + hex.EncodeToString(sum[:])
+
+	case ot.INSTANCE_TYPE.String():
+		return string(current.InstanceType), desired.InstanceType
+
+	case ot.VOLUME.String():
+		var attached []string
+		for _, m := range current.BlockDeviceMappings {
+			if m.Ebs != nil {
+				attached = append(attached, aws.ToString(m.DeviceName)+"="+aws.ToString(m.Ebs.VolumeId))
+			}
+		}
+		var changes []string
+		if op, ok := o.operations[opType].(*VolumeUpdateOperation); ok {
+			if _, commands, err := op.analyze(ctx); err == nil {
+				for _, cmd := range commands {
+					changes = append(changes, cmd.GetType())
+				}
+			}
+		}
+		return strings.Join(attached, ", "), strings.Join(changes, ", ")
+
+	case ot.POWER_STATE.String():
+		return string(current.State.Name), string(provider.DesiredPowerState(desired))
+	}
+
+	return "", ""
+}
+
+func currentTags(ctx UpdateContext) map[string]string {
+	tags := make(map[string]string, len(ctx.Current.Tags))
+	for _, tag := range ctx.Current.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return tags
+}
+
+// formatMap prints the entries of the map sorted by key.
+func formatMap(m map[string]string) string {
+	entries := make([]string, 0, len(m))
+	for k, v := range m {
+		entries = append(entries, k+"="+v)
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ", ")
+}
+
+func formatCurrentMetadata(ctx UpdateContext) string {
+	m := ctx.Current.MetadataOptions
+	if m == nil {
+		return ""
+	}
+	return formatMap(map[string]string{
+		"httpTokens":              string(m.HttpTokens),
+		"httpPutResponseHopLimit": fmt.Sprint(aws.ToInt32(m.HttpPutResponseHopLimit)),
+		"httpEndpoint":            string(m.HttpEndpoint),
+		"instanceMetadataTags":    string(m.InstanceMetadataTags),
+	})
+}
+
+func formatDesiredMetadata(m *v1alpha1.MetadataOptions) string {
+	if m == nil {
+		return ""
+	}
+	desired := map[string]string{}
+	if m.HTTPTokens != "" {
+		desired["httpTokens"] = m.HTTPTokens
+	}
+	if m.HTTPPutResponseHopLimit != nil {
+		desired["httpPutResponseHopLimit"] = fmt.Sprint(*m.HTTPPutResponseHopLimit)
+	}
+	if m.HTTPEndpoint != "" {
+		desired["httpEndpoint"] = m.HTTPEndpoint
+	}
+	if m.InstanceMetadataTags != "" {
+		desired["instanceMetadataTags"] = m.InstanceMetadataTags
+	}
+	return formatMap(desired)
+}
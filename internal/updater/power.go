@@ -0,0 +1,47 @@
+package updater
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+	ot "github.com/crossplane/provider-customcomputeprovider/internal/types"
+)
+
+// PowerStateOperation starts, stops or hibernates the instance to bring it
+// to the desired power state.
+type PowerStateOperation struct {
+	BaseOperation
+}
+
+func NewPowerStateOperation(logger logging.Logger) *PowerStateOperation {
+	return &PowerStateOperation{
+		BaseOperation: BaseOperation{opType: ot.POWER_STATE.String(), logger: logger},
+	}
+}
+
+func (o *PowerStateOperation) Execute(ctx UpdateContext) error {
+	desired := provider.DesiredPowerState(ctx.Desired)
+
+	switch ctx.Current.State.Name {
+	case types.InstanceStateNameStopped:
+		if desired == v1alpha1.PowerStateRunning {
+			o.logger.Info("starting instance", "instance", *ctx.Current.InstanceId)
+			return startInstance(ctx.Context, ctx.Client.Client, ctx.Current.InstanceId)
+		}
+
+	case types.InstanceStateNameRunning:
+		if desired != v1alpha1.PowerStateRunning {
+			o.logger.Info("stopping instance", "instance", *ctx.Current.InstanceId, "powerState", desired)
+			_, err := ctx.Client.Client.StopInstances(ctx.Context, &ec2.StopInstancesInput{
+				InstanceIds: []string{*ctx.Current.InstanceId},
+				Hibernate:   aws.Bool(desired == v1alpha1.PowerStateHibernated),
+			})
+			return err
+		}
+	}
+
+	return nil
+}
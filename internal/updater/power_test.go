@@ -0,0 +1,104 @@
+package updater
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider/fake"
+)
+
+func TestPowerStateExecute(t *testing.T) {
+	type want struct {
+		calls     []string
+		hibernate *bool
+	}
+
+	cases := map[string]struct {
+		reason string
+		state  types.InstanceStateName
+		power  v1alpha1.PowerState
+		want   want
+	}{
+		"Stop": {
+			reason: "A running instance meant to be stopped should be stopped.",
+			state:  types.InstanceStateNameRunning,
+			power:  v1alpha1.PowerStateStopped,
+			want:   want{calls: []string{"StopInstances"}, hibernate: aws.Bool(false)},
+		},
+		"Hibernate": {
+			reason: "A running instance meant to be hibernated should be hibernated.",
+			state:  types.InstanceStateNameRunning,
+			power:  v1alpha1.PowerStateHibernated,
+			want:   want{calls: []string{"StopInstances"}, hibernate: aws.Bool(true)},
+		},
+		"Start": {
+			reason: "A stopped instance meant to run should be started.",
+			state:  types.InstanceStateNameStopped,
+			power:  v1alpha1.PowerStateRunning,
+			want:   want{calls: []string{"StartInstances"}},
+		},
+		"StartByDefault": {
+			reason: "A stopped instance should be started when no power state is set.",
+			state:  types.InstanceStateNameStopped,
+			want:   want{calls: []string{"StartInstances"}},
+		},
+		"StoppedKeptStopped": {
+			reason: "A stopped instance meant to be hibernated should not be started.",
+			state:  types.InstanceStateNameStopped,
+			power:  v1alpha1.PowerStateHibernated,
+		},
+		"RunningKeptRunning": {
+			reason: "A running instance meant to run should be left alone.",
+			state:  types.InstanceStateNameRunning,
+			power:  v1alpha1.PowerStateRunning,
+		},
+		"Stopping": {
+			reason: "A stopping instance should not be started before it stopped.",
+			state:  types.InstanceStateNameStopping,
+			power:  v1alpha1.PowerStateRunning,
+		},
+		"Pending": {
+			reason: "A starting instance should not be stopped before it runs.",
+			state:  types.InstanceStateNamePending,
+			power:  v1alpha1.PowerStateStopped,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var hibernate *bool
+			fakeEC2 := &fake.EC2{Outputs: map[string]fake.Output{
+				"StopInstances": func(input interface{}) (interface{}, error) {
+					hibernate = input.(*ec2.StopInstancesInput).Hibernate
+					return &ec2.StopInstancesOutput{}, nil
+				},
+				"StartInstances": fake.Answer(&ec2.StartInstancesOutput{}),
+			}}
+			current := fake.Instance("i-1", tc.state)
+
+			err := NewPowerStateOperation(logging.NewNopLogger()).Execute(UpdateContext{
+				Context: context.Background(),
+				Current: &current,
+				Desired: &v1alpha1.InstanceConfig{PowerState: tc.power},
+				Status:  &v1alpha1.ComputeObservation{InstanceID: "i-1"},
+				Client:  &provider.EC2Client{Client: fakeEC2.Client()},
+			})
+			if err != nil {
+				t.Fatalf("Execute(...): %v", err)
+			}
+
+			got := want{calls: fakeEC2.Calls, hibernate: hibernate}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nExecute(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
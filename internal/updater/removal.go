@@ -0,0 +1,171 @@
+package updater
+
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+)
+
+const reasonVolumeRemoved event.Reason = "VolumeRemoved"
+
+// trackRemoval records a volume being detached so its removal policy is
+// applied once the detachment completed.
+func trackRemoval(status *v1alpha1.ComputeObservation, removal v1alpha1.VolumeRemoval) {
+	for _, r := range status.VolumeRemovals {
+		if r.VolumeID == removal.VolumeID {
+			return
+		}
+	}
+	status.VolumeRemovals = append(status.VolumeRemovals, removal)
+}
+
+// applyRemovals applies the removal policy of the volumes detached from the
+// instance. It never waits: a volume still detaching, or a snapshot still in
+// progress, is looked at again on the next reconcile.
+func (o *UpdateOrchestrator) applyRemovals(ctx UpdateContext) error {
+	removals := ctx.Status.VolumeRemovals
+	var remaining []v1alpha1.VolumeRemoval
+
+	for i := range removals {
+		done, err := o.applyRemoval(ctx, &removals[i])
+		if err != nil {
+			ctx.Status.VolumeRemovals = append(remaining, removals[i:]...)
+			return err
+		}
+		if !done {
+			remaining = append(remaining, removals[i])
+		}
+	}
+
+	ctx.Status.VolumeRemovals = remaining
+	return nil
+}
+
+func (o *UpdateOrchestrator) applyRemoval(ctx UpdateContext, r *v1alpha1.VolumeRemoval) (bool, error) {
+	output, err := ctx.Client.Client.DescribeVolumes(ctx.Context, &ec2.DescribeVolumesInput{
+		Filters: []types.Filter{{Name: aws.String("volume-id"), Values: []string{r.VolumeID}}},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if len(output.Volumes) == 0 {
+		o.logger.Info("removed volume no longer exists", "volume", r.VolumeID)
+		return true, nil
+	}
+
+	if output.Volumes[0].State != types.VolumeStateAvailable {
+		o.logger.Info("waiting for volume detachment", "volume", r.VolumeID, "state", output.Volumes[0].State)
+		return false, nil
+	}
+
+	if r.Snapshot || r.RemovalPolicy == v1alpha1.RemovalPolicySnapshot {
+		completed, err := o.snapshotVolume(ctx, r)
+		if err != nil || !completed {
+			return false, err
+		}
+	}
+
+	switch r.RemovalPolicy {
+	case v1alpha1.RemovalPolicySnapshot:
+		if err := deleteVolume(ctx, r.VolumeID); err != nil {
+			return false, err
+		}
+		o.recordRemoval(ctx, fmt.Sprintf("volume %s detached from %s, snapshot %s taken and volume deleted", r.VolumeID, r.DeviceName, r.SnapshotID))
+
+	case v1alpha1.RemovalPolicyDelete:
+		if err := deleteVolume(ctx, r.VolumeID); err != nil {
+			return false, err
+		}
+		o.recordRemoval(ctx, fmt.Sprintf("volume %s detached from %s and deleted", r.VolumeID, r.DeviceName))
+
+	default:
+		if r.Snapshot {
+			recordReplacedVolume(ctx.Status, r)
+			o.recordRemoval(ctx, fmt.Sprintf("volume %s detached from %s, snapshot %s taken and volume retained", r.VolumeID, r.DeviceName, r.SnapshotID))
+			break
+		}
+		o.recordRemoval(ctx, fmt.Sprintf("volume %s detached from %s and retained", r.VolumeID, r.DeviceName))
+	}
+
+	return true, nil
+}
+
+// recordReplacedVolume records the retained volume and its snapshot on the
+// storage entry the volume was replaced for.
+func recordReplacedVolume(status *v1alpha1.ComputeObservation, r *v1alpha1.VolumeRemoval) {
+	if status.StorageVolumes == nil {
+		status.StorageVolumes = make(map[string]v1alpha1.StorageVolume)
+	}
+	volume := status.StorageVolumes[r.DeviceName]
+	volume.ReplacedVolumeID = r.VolumeID
+	volume.SnapshotID = r.SnapshotID
+	status.StorageVolumes[r.DeviceName] = volume
+}
+
+// snapshotVolume takes the final snapshot of the volume, it reports whether
+// the snapshot completed.
+func (o *UpdateOrchestrator) snapshotVolume(ctx UpdateContext, r *v1alpha1.VolumeRemoval) (bool, error) {
+	if r.SnapshotID == "" {
+		snapshot, err := ctx.Client.Client.CreateSnapshot(ctx.Context, &ec2.CreateSnapshotInput{
+			VolumeId:    &r.VolumeID,
+			Description: aws.String(fmt.Sprintf("Final snapshot of %s for %s", r.DeviceName, ctx.Resource.GetName())),
+			TagSpecifications: []types.TagSpecification{
+				{
+					ResourceType: types.ResourceTypeSnapshot,
+					Tags: []types.Tag{
+						{Key: aws.String("Name"), Value: aws.String(ctx.Resource.GetName())},
+						{Key: aws.String(provider.OwnerTagKey), Value: aws.String(string(ctx.Owner))},
+					},
+				},
+			},
+		})
+		if err != nil {
+			return false, fmt.Errorf("failed to snapshot volume %s: %w", r.VolumeID, err)
+		}
+
+		r.SnapshotID = aws.ToString(snapshot.SnapshotId)
+		o.logger.Info("final snapshot started", "volume", r.VolumeID, "snapshot", r.SnapshotID)
+		return false, nil
+	}
+
+	output, err := ctx.Client.Client.DescribeSnapshots(ctx.Context, &ec2.DescribeSnapshotsInput{
+		SnapshotIds: []string{r.SnapshotID},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if len(output.Snapshots) == 0 {
+		return false, fmt.Errorf("snapshot %s of volume %s not found", r.SnapshotID, r.VolumeID)
+	}
+
+	switch output.Snapshots[0].State {
+	case types.SnapshotStateCompleted:
+		return true, nil
+	case types.SnapshotStateError:
+		return false, fmt.Errorf("snapshot %s of volume %s failed: %s", r.SnapshotID, r.VolumeID, aws.ToString(output.Snapshots[0].StateMessage))
+	}
+
+	return false, nil
+}
+
+func deleteVolume(ctx UpdateContext, volumeID string) error {
+	_, err := ctx.Client.Client.DeleteVolume(ctx.Context, &ec2.DeleteVolumeInput{
+		VolumeId: &volumeID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete volume %s: %w", volumeID, err)
+	}
+	return nil
+}
+
+func (o *UpdateOrchestrator) recordRemoval(ctx UpdateContext, message string) {
+	o.logger.Info(message)
+	ctx.Recorder.Event(ctx.Resource, event.Normal(reasonVolumeRemoved, message))
+}
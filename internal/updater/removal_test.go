@@ -0,0 +1,173 @@
+package updater
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider/fake"
+)
+
+// recorder keeps the reasons of the events recorded on a resource.
+type recorder struct {
+	reasons []event.Reason
+}
+
+func (r *recorder) Event(_ runtime.Object, e event.Event) { r.reasons = append(r.reasons, e.Reason) }
+
+func (r *recorder) WithAnnotations(_ ...string) event.Recorder { return r }
+
+func TestApplyRemovals(t *testing.T) {
+	available := fake.Answer(&ec2.DescribeVolumesOutput{Volumes: []types.Volume{
+		{VolumeId: aws.String("vol-data"), State: types.VolumeStateAvailable},
+	}})
+	snapshot := func(state types.SnapshotState) fake.Output {
+		return fake.Answer(&ec2.DescribeSnapshotsOutput{Snapshots: []types.Snapshot{
+			{SnapshotId: aws.String("snap-1"), State: state},
+		}})
+	}
+
+	type want struct {
+		calls          []string
+		removals       []v1alpha1.VolumeRemoval
+		snapshotTags   map[string]string
+		storageVolumes map[string]v1alpha1.StorageVolume
+		events         []event.Reason
+	}
+
+	cases := map[string]struct {
+		reason   string
+		removal  v1alpha1.VolumeRemoval
+		describe fake.Output
+		snapshot fake.Output
+		want     want
+	}{
+		"Detaching": {
+			reason:  "A volume still detaching should be looked at again on the next reconcile.",
+			removal: v1alpha1.VolumeRemoval{VolumeID: "vol-data", DeviceName: "/dev/sdf", RemovalPolicy: v1alpha1.RemovalPolicyDelete},
+			describe: fake.Answer(&ec2.DescribeVolumesOutput{Volumes: []types.Volume{
+				{VolumeId: aws.String("vol-data"), State: types.VolumeStateInUse},
+			}}),
+			want: want{
+				calls:    []string{"DescribeVolumes"},
+				removals: []v1alpha1.VolumeRemoval{{VolumeID: "vol-data", DeviceName: "/dev/sdf", RemovalPolicy: v1alpha1.RemovalPolicyDelete}},
+			},
+		},
+		"Gone": {
+			reason:   "A volume that no longer exists should be forgotten.",
+			removal:  v1alpha1.VolumeRemoval{VolumeID: "vol-data", DeviceName: "/dev/sdf", RemovalPolicy: v1alpha1.RemovalPolicyDelete},
+			describe: fake.Answer(&ec2.DescribeVolumesOutput{}),
+			want:     want{calls: []string{"DescribeVolumes"}},
+		},
+		"Retain": {
+			reason:   "A retained volume should be left alone once detached.",
+			removal:  v1alpha1.VolumeRemoval{VolumeID: "vol-data", DeviceName: "/dev/sdf", RemovalPolicy: v1alpha1.RemovalPolicyRetain},
+			describe: available,
+			want:     want{calls: []string{"DescribeVolumes"}, events: []event.Reason{reasonVolumeRemoved}},
+		},
+		"Delete": {
+			reason:   "A volume under the delete policy should be deleted once detached.",
+			removal:  v1alpha1.VolumeRemoval{VolumeID: "vol-data", DeviceName: "/dev/sdf", RemovalPolicy: v1alpha1.RemovalPolicyDelete},
+			describe: available,
+			want:     want{calls: []string{"DescribeVolumes", "DeleteVolume"}, events: []event.Reason{reasonVolumeRemoved}},
+		},
+		"SnapshotStarted": {
+			reason:   "A volume under the snapshot policy should have a tagged snapshot taken before anything else.",
+			removal:  v1alpha1.VolumeRemoval{VolumeID: "vol-data", DeviceName: "/dev/sdf", RemovalPolicy: v1alpha1.RemovalPolicySnapshot},
+			describe: available,
+			want: want{
+				calls:        []string{"DescribeVolumes", "CreateSnapshot"},
+				removals:     []v1alpha1.VolumeRemoval{{VolumeID: "vol-data", DeviceName: "/dev/sdf", RemovalPolicy: v1alpha1.RemovalPolicySnapshot, SnapshotID: "snap-1"}},
+				snapshotTags: map[string]string{"Name": "web", provider.OwnerTagKey: "compute-uid"},
+			},
+		},
+		"SnapshotPending": {
+			reason:   "A volume should not be deleted while its snapshot is pending.",
+			removal:  v1alpha1.VolumeRemoval{VolumeID: "vol-data", DeviceName: "/dev/sdf", RemovalPolicy: v1alpha1.RemovalPolicySnapshot, SnapshotID: "snap-1"},
+			describe: available,
+			snapshot: snapshot(types.SnapshotStatePending),
+			want: want{
+				calls:    []string{"DescribeVolumes", "DescribeSnapshots"},
+				removals: []v1alpha1.VolumeRemoval{{VolumeID: "vol-data", DeviceName: "/dev/sdf", RemovalPolicy: v1alpha1.RemovalPolicySnapshot, SnapshotID: "snap-1"}},
+			},
+		},
+		"SnapshotCompleted": {
+			reason:   "A volume should be deleted once its snapshot completed.",
+			removal:  v1alpha1.VolumeRemoval{VolumeID: "vol-data", DeviceName: "/dev/sdf", RemovalPolicy: v1alpha1.RemovalPolicySnapshot, SnapshotID: "snap-1"},
+			describe: available,
+			snapshot: snapshot(types.SnapshotStateCompleted),
+			want: want{
+				calls:  []string{"DescribeVolumes", "DescribeSnapshots", "DeleteVolume"},
+				events: []event.Reason{reasonVolumeRemoved},
+			},
+		},
+		"ReplacedOnShrink": {
+			reason:   "A volume replaced to shrink it should be retained and recorded along with its snapshot.",
+			removal:  v1alpha1.VolumeRemoval{VolumeID: "vol-data", DeviceName: "/dev/sdf", RemovalPolicy: v1alpha1.RemovalPolicyRetain, Snapshot: true, SnapshotID: "snap-1"},
+			describe: available,
+			snapshot: snapshot(types.SnapshotStateCompleted),
+			want: want{
+				calls:          []string{"DescribeVolumes", "DescribeSnapshots"},
+				storageVolumes: map[string]v1alpha1.StorageVolume{"/dev/sdf": {ReplacedVolumeID: "vol-data", SnapshotID: "snap-1"}},
+				events:         []event.Reason{reasonVolumeRemoved},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var snapshotTags map[string]string
+			fakeEC2 := &fake.EC2{Outputs: map[string]fake.Output{
+				"DescribeVolumes": tc.describe,
+				"CreateSnapshot": func(input interface{}) (interface{}, error) {
+					snapshotTags = map[string]string{}
+					for _, spec := range input.(*ec2.CreateSnapshotInput).TagSpecifications {
+						for _, tag := range spec.Tags {
+							snapshotTags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+						}
+					}
+					return &ec2.CreateSnapshotOutput{SnapshotId: aws.String("snap-1")}, nil
+				},
+				"DescribeSnapshots": tc.snapshot,
+				"DeleteVolume":      fake.Answer(&ec2.DeleteVolumeOutput{}),
+			}}
+			cr := &v1alpha1.Compute{}
+			cr.SetName("web")
+			status := &v1alpha1.ComputeObservation{VolumeRemovals: []v1alpha1.VolumeRemoval{tc.removal}}
+			r := &recorder{}
+
+			o := NewUpdateOrchestrator(logging.NewNopLogger())
+			err := o.applyRemovals(UpdateContext{
+				Context:  context.Background(),
+				Status:   status,
+				Owner:    "compute-uid",
+				Client:   &provider.EC2Client{Client: fakeEC2.Client()},
+				Resource: cr,
+				Recorder: r,
+			})
+			if err != nil {
+				t.Fatalf("applyRemovals(...): %v", err)
+			}
+
+			got := want{
+				calls:          fakeEC2.Calls,
+				removals:       status.VolumeRemovals,
+				snapshotTags:   snapshotTags,
+				storageVolumes: status.StorageVolumes,
+				events:         r.reasons,
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\napplyRemovals(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,409 @@
+package updater
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+	ot "github.com/crossplane/provider-customcomputeprovider/internal/types"
+)
+
+const (
+	phaseLaunch            = "Launch"
+	phaseWaitLaunched      = "WaitLaunched"
+	phaseStopPrevious      = "StopPrevious"
+	phaseDetachVolumes     = "DetachVolumes"
+	phaseAttachVolumes     = "AttachVolumes"
+	phaseTerminatePrevious = "TerminatePrevious"
+)
+
+// reservedTagPrefix starts the keys of the tags AWS sets itself.
+const reservedTagPrefix = "aws:"
+
+// ReplacementOperation replaces the instance with a new one built from the
+// desired configuration. A replacement spans several reconciles, its progress
+// is kept in the status so every call resumes where the previous one stopped.
+type ReplacementOperation struct {
+	BaseOperation
+}
+
+func NewReplacementOperation(logger logging.Logger) *ReplacementOperation {
+	return &ReplacementOperation{
+		BaseOperation: BaseOperation{opType: ot.AMI.String(), disruptive: true, logger: logger},
+	}
+}
+
+// ReplacesInstance reports that an image change always replaces the instance.
+func (o *ReplacementOperation) ReplacesInstance(_ UpdateContext) bool {
+	return true
+}
+
+func (o *ReplacementOperation) Execute(ctx UpdateContext) error {
+	if ctx.Status.Replacement == nil {
+		ctx.Status.Replacement = o.plan(ctx)
+		o.logger.Info("starting instance replacement",
+			"instance", ctx.Status.Replacement.PreviousInstanceID,
+			"strategy", ctx.Status.Replacement.Strategy,
+			"volumes", ctx.Status.Replacement.Volumes)
+	}
+
+	r := ctx.Status.Replacement
+	phases := replacementPhases(r)
+
+	for i := phaseIndex(phases, r.Phase); i < len(phases); i++ {
+		r.Phase = phases[i]
+
+		done, err := o.runPhase(ctx, r)
+		if err != nil {
+			return fmt.Errorf("instance replacement failed during %s: %w", phases[i], err)
+		}
+
+		if !done {
+			o.logger.Info("instance replacement in progress", "phase", r.Phase)
+			return nil
+		}
+	}
+
+	o.logger.Info("instance replacement completed",
+		"previous", r.PreviousInstanceID,
+		"instance", ctx.Status.InstanceID)
+	ctx.Status.Replacement = nil
+	ctx.Status.ReplacementAttempts = 0
+	return nil
+}
+
+func (o *ReplacementOperation) plan(ctx UpdateContext) *v1alpha1.Replacement {
+	strategy := ctx.Desired.ReplacementPolicy.Strategy
+	if strategy == "" {
+		strategy = v1alpha1.ReplacementStrategyCreateBeforeDestroy
+	}
+
+	r := &v1alpha1.Replacement{
+		PreviousInstanceID: *ctx.Current.InstanceId,
+		Strategy:           strategy,
+	}
+
+	if !ctx.Desired.ReplacementPolicy.ReattachVolumes {
+		return r
+	}
+
+	for _, mapping := range ctx.Current.BlockDeviceMappings {
+		if mapping.Ebs == nil || aws.ToString(mapping.DeviceName) == aws.ToString(ctx.Current.RootDeviceName) {
+			continue
+		}
+
+		r.Volumes = append(r.Volumes, v1alpha1.VolumeAttachment{
+			VolumeID:   aws.ToString(mapping.Ebs.VolumeId),
+			DeviceName: aws.ToString(mapping.DeviceName),
+		})
+	}
+
+	return r
+}
+
+func replacementPhases(r *v1alpha1.Replacement) []string {
+	reattach := len(r.Volumes) > 0
+
+	var phases []string
+	if r.Strategy == v1alpha1.ReplacementStrategyRecreate {
+		if reattach {
+			phases = append(phases, phaseStopPrevious, phaseDetachVolumes)
+		}
+		phases = append(phases, phaseTerminatePrevious, phaseLaunch, phaseWaitLaunched)
+		if reattach {
+			phases = append(phases, phaseAttachVolumes)
+		}
+		return phases
+	}
+
+	// The previous instance is kept until the new one runs.
+	phases = append(phases, phaseLaunch, phaseWaitLaunched)
+	if reattach {
+		phases = append(phases, phaseStopPrevious, phaseDetachVolumes, phaseAttachVolumes)
+	}
+	return append(phases, phaseTerminatePrevious)
+}
+
+// replacementDisrupted reports whether the replacement already stopped or
+// terminated the previous instance.
+func replacementDisrupted(r *v1alpha1.Replacement) bool {
+	phases := replacementPhases(r)
+	for _, phase := range phases[:phaseIndex(phases, r.Phase)+1] {
+		if phase == phaseStopPrevious || phase == phaseTerminatePrevious {
+			return true
+		}
+	}
+	return false
+}
+
+func phaseIndex(phases []string, phase string) int {
+	for i, p := range phases {
+		if p == phase {
+			return i
+		}
+	}
+	return 0
+}
+
+func (o *ReplacementOperation) runPhase(ctx UpdateContext, r *v1alpha1.Replacement) (bool, error) {
+	switch r.Phase {
+	case phaseLaunch:
+		return o.launch(ctx, r)
+	case phaseWaitLaunched:
+		return o.waitLaunched(ctx, r)
+	case phaseStopPrevious:
+		return o.stopPrevious(ctx, r)
+	case phaseDetachVolumes:
+		return o.detachVolumes(ctx, r)
+	case phaseAttachVolumes:
+		return o.attachVolumes(ctx, r)
+	case phaseTerminatePrevious:
+		return o.terminatePrevious(ctx, r)
+	}
+
+	return false, fmt.Errorf("unknown replacement phase %q", r.Phase)
+}
+
+func (o *ReplacementOperation) launch(ctx UpdateContext, r *v1alpha1.Replacement) (bool, error) {
+	if ctx.Status.InstanceID != r.PreviousInstanceID {
+		return true, nil
+	}
+
+	// Every attempt gets its own token, the one of a failed attempt would
+	// hand back the failed instance.
+	seed := fmt.Sprintf("%s/%d", r.PreviousInstanceID, ctx.Status.ReplacementAttempts)
+	output, err := ctx.Client.CreateInstance(ctx.Context, replacementConfig(ctx, r),
+		provider.WithClientToken(provider.ClientToken(ctx.Owner, seed)),
+		provider.WithOwner(ctx.Owner),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	ctx.Status.InstanceID = *output.Instances[0].InstanceId
+	ctx.Status.State = string(output.Instances[0].State.Name)
+
+	o.logger.Info("replacement instance launched",
+		"previous", r.PreviousInstanceID,
+		"instance", ctx.Status.InstanceID)
+	return true, nil
+}
+
+// waitLaunched reports whether the new instance is running. A new instance
+// that fails to start is terminated. The replacement then ends and keeps the
+// previous instance, or launches another instance when the previous one was
+// already terminated.
+func (o *ReplacementOperation) waitLaunched(ctx UpdateContext, r *v1alpha1.Replacement) (bool, error) {
+	instance, err := ctx.Client.GetInstanceByID(ctx.Context, ctx.Status.InstanceID)
+	if err != nil {
+		return false, err
+	}
+
+	switch instance.State.Name {
+	case types.InstanceStateNameRunning:
+		return true, nil
+	case types.InstanceStateNamePending:
+		return false, nil
+	}
+
+	failed := ctx.Status.InstanceID
+	if _, err := ctx.Client.Client.TerminateInstances(ctx.Context, &ec2.TerminateInstancesInput{
+		InstanceIds: []string{failed},
+	}); err != nil {
+		return false, fmt.Errorf("failed to terminate replacement instance %s: %w", failed, err)
+	}
+
+	ctx.Status.InstanceID = r.PreviousInstanceID
+	ctx.Status.ReplacementAttempts++
+
+	if r.Strategy == v1alpha1.ReplacementStrategyRecreate {
+		o.logger.Info("replacement instance failed to start, launching another one",
+			"previous", r.PreviousInstanceID,
+			"instance", failed,
+			"state", instance.State.Name)
+
+		r.Phase = phaseLaunch
+		return false, fmt.Errorf("replacement instance %s is %s, launching another one",
+			failed, instance.State.Name)
+	}
+
+	o.logger.Info("replacement instance failed to start, keeping the previous instance",
+		"previous", r.PreviousInstanceID,
+		"instance", failed,
+		"state", instance.State.Name)
+
+	ctx.Status.Replacement = nil
+	return false, fmt.Errorf("replacement instance %s is %s, keeping instance %s",
+		failed, instance.State.Name, r.PreviousInstanceID)
+}
+
+// replacementConfig builds the configuration of the new instance. It keeps
+// the tags of the current instance, desired tags taking precedence, and skips
+// the storage entries that will be served by reattached volumes. Tags
+// reserved by AWS are left out, EC2 refuses to launch an instance with them.
+func replacementConfig(ctx UpdateContext, r *v1alpha1.Replacement) v1alpha1.InstanceConfig {
+	config := *ctx.Desired
+
+	config.InstanceTags = make(map[string]string, len(ctx.Current.Tags)+len(ctx.Desired.InstanceTags))
+	for _, tag := range ctx.Current.Tags {
+		if strings.HasPrefix(aws.ToString(tag.Key), reservedTagPrefix) {
+			continue
+		}
+		config.InstanceTags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	for key, value := range ctx.Desired.InstanceTags {
+		config.InstanceTags[key] = value
+	}
+	config.InstanceTags["Name"] = ctx.Desired.InstanceName
+
+	reattached := make(map[string]struct{}, len(r.Volumes))
+	for _, v := range r.Volumes {
+		reattached[v.DeviceName] = struct{}{}
+	}
+
+	config.Storage = nil
+	for _, storage := range ctx.Desired.Storage {
+		if _, found := reattached[storage.DeviceName]; !found {
+			config.Storage = append(config.Storage, storage)
+		}
+	}
+
+	return config
+}
+
+func (o *ReplacementOperation) stopPrevious(ctx UpdateContext, r *v1alpha1.Replacement) (bool, error) {
+	instance, err := ctx.Client.GetInstanceByID(ctx.Context, r.PreviousInstanceID)
+	if err != nil {
+		return false, err
+	}
+
+	// An instance reclaimed by EC2 is already gone, it has nothing to stop.
+	switch instance.State.Name {
+	case types.InstanceStateNameStopped, types.InstanceStateNameTerminated:
+		return true, nil
+	case types.InstanceStateNameStopping, types.InstanceStateNameShuttingDown:
+		return false, nil
+	}
+
+	return false, stopInstance(ctx.Context, ctx.Client.Client, instance.InstanceId)
+}
+
+func (o *ReplacementOperation) detachVolumes(ctx UpdateContext, r *v1alpha1.Replacement) (bool, error) {
+	volumes, err := describeReplacementVolumes(ctx, r)
+	if err != nil {
+		return false, err
+	}
+
+	detached := true
+	for _, volume := range volumes {
+		if volume.State == types.VolumeStateAvailable {
+			continue
+		}
+
+		detached = false
+		for _, attachment := range volume.Attachments {
+			if aws.ToString(attachment.InstanceId) != r.PreviousInstanceID || attachment.State != types.VolumeAttachmentStateAttached {
+				continue
+			}
+
+			if _, err := ctx.Client.Client.DetachVolume(ctx.Context, &ec2.DetachVolumeInput{
+				VolumeId:   volume.VolumeId,
+				InstanceId: &r.PreviousInstanceID,
+			}); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	return detached, nil
+}
+
+func (o *ReplacementOperation) attachVolumes(ctx UpdateContext, r *v1alpha1.Replacement) (bool, error) {
+	instance, err := ctx.Client.GetInstanceByID(ctx.Context, ctx.Status.InstanceID)
+	if err != nil {
+		return false, err
+	}
+
+	if instance.State.Name == types.InstanceStateNamePending {
+		return false, nil
+	}
+
+	volumes, err := describeReplacementVolumes(ctx, r)
+	if err != nil {
+		return false, err
+	}
+
+	devices := make(map[string]string, len(r.Volumes))
+	for _, v := range r.Volumes {
+		devices[v.VolumeID] = v.DeviceName
+	}
+
+	attached := true
+	for _, volume := range volumes {
+		if volume.State != types.VolumeStateAvailable {
+			if !attachedTo(volume, ctx.Status.InstanceID) {
+				attached = false
+			}
+			continue
+		}
+
+		attached = false
+		device := devices[*volume.VolumeId]
+		if _, err := ctx.Client.Client.AttachVolume(ctx.Context, &ec2.AttachVolumeInput{
+			Device:     &device,
+			InstanceId: &ctx.Status.InstanceID,
+			VolumeId:   volume.VolumeId,
+		}); err != nil {
+			return false, err
+		}
+	}
+
+	return attached, nil
+}
+
+func (o *ReplacementOperation) terminatePrevious(ctx UpdateContext, r *v1alpha1.Replacement) (bool, error) {
+	instance, err := ctx.Client.GetInstanceByID(ctx.Context, r.PreviousInstanceID)
+	if err != nil {
+		return false, err
+	}
+
+	switch instance.State.Name {
+	case types.InstanceStateNameShuttingDown, types.InstanceStateNameTerminated:
+		return true, nil
+	}
+
+	_, err = ctx.Client.Client.TerminateInstances(ctx.Context, &ec2.TerminateInstancesInput{
+		InstanceIds: []string{r.PreviousInstanceID},
+	})
+	return err == nil, err
+}
+
+func describeReplacementVolumes(ctx UpdateContext, r *v1alpha1.Replacement) ([]types.Volume, error) {
+	volumeIDs := make([]string, len(r.Volumes))
+	for i, v := range r.Volumes {
+		volumeIDs[i] = v.VolumeID
+	}
+
+	output, err := ctx.Client.Client.DescribeVolumes(ctx.Context, &ec2.DescribeVolumesInput{
+		VolumeIds: volumeIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return output.Volumes, nil
+}
+
+func attachedTo(volume types.Volume, instanceID string) bool {
+	for _, attachment := range volume.Attachments {
+		if aws.ToString(attachment.InstanceId) == instanceID && attachment.State == types.VolumeAttachmentStateAttached {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,310 @@
+package updater
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/google/go-cmp/cmp"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider/fake"
+)
+
+func TestReplacementWaitLaunched(t *testing.T) {
+	type want struct {
+		err         bool
+		instanceID  string
+		attempts    int
+		replacement *v1alpha1.Replacement
+		calls       []string
+	}
+
+	cases := map[string]struct {
+		reason   string
+		strategy v1alpha1.ReplacementStrategy
+		state    types.InstanceStateName
+		want     want
+	}{
+		"Pending": {
+			reason:   "The previous instance should be kept while the new one is pending.",
+			strategy: v1alpha1.ReplacementStrategyCreateBeforeDestroy,
+			state:    types.InstanceStateNamePending,
+			want: want{
+				instanceID: "i-new",
+				replacement: &v1alpha1.Replacement{
+					PreviousInstanceID: "i-previous",
+					Strategy:           v1alpha1.ReplacementStrategyCreateBeforeDestroy,
+					Phase:              phaseWaitLaunched,
+				},
+				calls: []string{"DescribeInstances"},
+			},
+		},
+		"Running": {
+			reason:   "The previous instance should be terminated once the new one runs.",
+			strategy: v1alpha1.ReplacementStrategyCreateBeforeDestroy,
+			state:    types.InstanceStateNameRunning,
+			want: want{
+				instanceID: "i-new",
+				calls:      []string{"DescribeInstances", "DescribeInstances", "TerminateInstances"},
+			},
+		},
+		"Terminated": {
+			reason:   "A new instance that failed to start should be terminated, the replacement ended and the previous instance kept.",
+			strategy: v1alpha1.ReplacementStrategyCreateBeforeDestroy,
+			state:    types.InstanceStateNameTerminated,
+			want: want{
+				err:        true,
+				instanceID: "i-previous",
+				attempts:   1,
+				calls:      []string{"DescribeInstances", "TerminateInstances"},
+			},
+		},
+		"Stopped": {
+			reason:   "A new instance that stopped on its own should be terminated rather than left behind.",
+			strategy: v1alpha1.ReplacementStrategyCreateBeforeDestroy,
+			state:    types.InstanceStateNameStopped,
+			want: want{
+				err:        true,
+				instanceID: "i-previous",
+				attempts:   1,
+				calls:      []string{"DescribeInstances", "TerminateInstances"},
+			},
+		},
+		"RecreateTerminated": {
+			reason:   "A new instance that failed to start after the previous one was terminated should be launched again.",
+			strategy: v1alpha1.ReplacementStrategyRecreate,
+			state:    types.InstanceStateNameShuttingDown,
+			want: want{
+				err:        true,
+				instanceID: "i-previous",
+				attempts:   1,
+				replacement: &v1alpha1.Replacement{
+					PreviousInstanceID: "i-previous",
+					Strategy:           v1alpha1.ReplacementStrategyRecreate,
+					Phase:              phaseLaunch,
+				},
+				calls: []string{"DescribeInstances", "TerminateInstances"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fakeEC2 := &fake.EC2{Outputs: map[string]fake.Output{
+				"DescribeInstances": fake.DescribeInstances(
+					fake.Instance("i-previous", types.InstanceStateNameRunning),
+					fake.Instance("i-new", tc.state),
+				),
+				"TerminateInstances": fake.Answer(&ec2.TerminateInstancesOutput{}),
+			}}
+
+			status := &v1alpha1.ComputeObservation{
+				InstanceID: "i-new",
+				Replacement: &v1alpha1.Replacement{
+					PreviousInstanceID: "i-previous",
+					Strategy:           tc.strategy,
+					Phase:              phaseWaitLaunched,
+				},
+			}
+
+			err := NewReplacementOperation(logging.NewNopLogger()).Execute(UpdateContext{
+				Context: context.Background(),
+				Current: &types.Instance{
+					InstanceId: aws.String("i-new"),
+					State:      &types.InstanceState{Name: tc.state},
+				},
+				Desired: &v1alpha1.InstanceConfig{},
+				Status:  status,
+				Client:  &provider.EC2Client{Client: fakeEC2.Client()},
+			})
+
+			got := want{
+				err:         err != nil,
+				instanceID:  status.InstanceID,
+				attempts:    status.ReplacementAttempts,
+				replacement: status.Replacement,
+				calls:       fakeEC2.Calls,
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nExecute(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestReplacementLaunchToken(t *testing.T) {
+	owner := k8stypes.UID("compute-uid")
+
+	cases := map[string]struct {
+		reason   string
+		attempts int
+		want     string
+	}{
+		"FirstAttempt": {
+			reason: "The first launch should be seeded with the previous instance.",
+			want:   provider.ClientToken(owner, "i-previous/0"),
+		},
+		"Retry": {
+			reason:   "A launch after a failed attempt should not reuse the token of the failed instance.",
+			attempts: 1,
+			want:     provider.ClientToken(owner, "i-previous/1"),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var token string
+			fakeEC2 := &fake.EC2{Outputs: map[string]fake.Output{
+				"RunInstances": func(input interface{}) (interface{}, error) {
+					token = aws.ToString(input.(*ec2.RunInstancesInput).ClientToken)
+					return &ec2.RunInstancesOutput{Instances: []types.Instance{fake.Instance("i-new", types.InstanceStateNamePending)}}, nil
+				},
+				"DescribeInstances": fake.DescribeInstances(fake.Instance("i-new", types.InstanceStateNamePending)),
+			}}
+
+			status := &v1alpha1.ComputeObservation{
+				InstanceID:          "i-previous",
+				ReplacementAttempts: tc.attempts,
+				Replacement: &v1alpha1.Replacement{
+					PreviousInstanceID: "i-previous",
+					Strategy:           v1alpha1.ReplacementStrategyCreateBeforeDestroy,
+					Phase:              phaseLaunch,
+				},
+			}
+
+			err := NewReplacementOperation(logging.NewNopLogger()).Execute(UpdateContext{
+				Context: context.Background(),
+				Current: &types.Instance{InstanceId: aws.String("i-previous")},
+				Desired: &v1alpha1.InstanceConfig{InstanceName: "web"},
+				Status:  status,
+				Client:  &provider.EC2Client{Client: fakeEC2.Client()},
+				Owner:   owner,
+			})
+			if err != nil {
+				t.Fatalf("Execute(...): %v", err)
+			}
+
+			if diff := cmp.Diff(tc.want, token); diff != "" {
+				t.Errorf("\n%s\nExecute(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestReplacementPhases(t *testing.T) {
+	volumes := []v1alpha1.VolumeAttachment{{VolumeID: "vol-data", DeviceName: "/dev/sdf"}}
+
+	cases := map[string]struct {
+		reason      string
+		replacement *v1alpha1.Replacement
+		want        []string
+	}{
+		"CreateBeforeDestroy": {
+			reason:      "The previous instance should only be terminated once the new one runs.",
+			replacement: &v1alpha1.Replacement{Strategy: v1alpha1.ReplacementStrategyCreateBeforeDestroy},
+			want:        []string{phaseLaunch, phaseWaitLaunched, phaseTerminatePrevious},
+		},
+		"CreateBeforeDestroyReattach": {
+			reason:      "The volumes should be moved once the new instance runs and before the previous one is terminated.",
+			replacement: &v1alpha1.Replacement{Strategy: v1alpha1.ReplacementStrategyCreateBeforeDestroy, Volumes: volumes},
+			want: []string{phaseLaunch, phaseWaitLaunched, phaseStopPrevious, phaseDetachVolumes,
+				phaseAttachVolumes, phaseTerminatePrevious},
+		},
+		"Recreate": {
+			reason:      "The previous instance should be terminated before the new one is launched.",
+			replacement: &v1alpha1.Replacement{Strategy: v1alpha1.ReplacementStrategyRecreate},
+			want:        []string{phaseTerminatePrevious, phaseLaunch, phaseWaitLaunched},
+		},
+		"RecreateReattach": {
+			reason:      "The volumes should be detached before the previous instance is terminated and attached to the new one.",
+			replacement: &v1alpha1.Replacement{Strategy: v1alpha1.ReplacementStrategyRecreate, Volumes: volumes},
+			want: []string{phaseStopPrevious, phaseDetachVolumes, phaseTerminatePrevious,
+				phaseLaunch, phaseWaitLaunched, phaseAttachVolumes},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := replacementPhases(tc.replacement)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nreplacementPhases(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestReplacementConfig(t *testing.T) {
+	cases := map[string]struct {
+		reason      string
+		current     *types.Instance
+		desired     *v1alpha1.InstanceConfig
+		replacement *v1alpha1.Replacement
+		want        v1alpha1.InstanceConfig
+	}{
+		"Tags": {
+			reason: "The tags of the current instance should be kept, desired tags and name taking precedence.",
+			current: &types.Instance{Tags: []types.Tag{
+				{Key: aws.String("Name"), Value: aws.String("old")},
+				{Key: aws.String("team"), Value: aws.String("infra")},
+				{Key: aws.String("env"), Value: aws.String("dev")},
+			}},
+			desired: &v1alpha1.InstanceConfig{
+				InstanceName: "new",
+				InstanceTags: map[string]string{"env": "prod"},
+			},
+			replacement: &v1alpha1.Replacement{},
+			want: v1alpha1.InstanceConfig{
+				InstanceName: "new",
+				InstanceTags: map[string]string{"Name": "new", "team": "infra", "env": "prod"},
+			},
+		},
+		"ReservedTags": {
+			reason: "Tags reserved by AWS should not be copied to the new instance.",
+			current: &types.Instance{Tags: []types.Tag{
+				{Key: aws.String("aws:cloudformation:stack-name"), Value: aws.String("stack")},
+				{Key: aws.String("aws:autoscaling:groupName"), Value: aws.String("group")},
+				{Key: aws.String("team"), Value: aws.String("infra")},
+			}},
+			desired:     &v1alpha1.InstanceConfig{InstanceName: "new"},
+			replacement: &v1alpha1.Replacement{},
+			want: v1alpha1.InstanceConfig{
+				InstanceName: "new",
+				InstanceTags: map[string]string{"Name": "new", "team": "infra"},
+			},
+		},
+		"ReattachedStorage": {
+			reason:  "Storage served by a reattached volume should not be created again.",
+			current: &types.Instance{},
+			desired: &v1alpha1.InstanceConfig{
+				InstanceName: "new",
+				Storage: []v1alpha1.Storage{
+					{DeviceName: "/dev/sda1"},
+					{DeviceName: "/dev/sdf"},
+				},
+			},
+			replacement: &v1alpha1.Replacement{
+				Volumes: []v1alpha1.VolumeAttachment{{VolumeID: "vol-data", DeviceName: "/dev/sdf"}},
+			},
+			want: v1alpha1.InstanceConfig{
+				InstanceName: "new",
+				InstanceTags: map[string]string{"Name": "new"},
+				Storage:      []v1alpha1.Storage{{DeviceName: "/dev/sda1"}},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := replacementConfig(UpdateContext{Current: tc.current, Desired: tc.desired}, tc.replacement)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nreplacementConfig(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package updater
 import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	ot "github.com/crossplane/provider-customcomputeprovider/internal/types"
 )
 
 type SecurityGroupUpdateOperation struct {
@@ -11,7 +12,7 @@ type SecurityGroupUpdateOperation struct {
 
 func NewSecurityGroupUpdateOperation(logger logging.Logger) *SecurityGroupUpdateOperation {
 	return &SecurityGroupUpdateOperation{
-		BaseOperation: BaseOperation{opType: "SECURITY_GROUP", logger: logger},
+		BaseOperation: BaseOperation{opType: ot.SECURITY_GROUPS.String(), logger: logger},
 	}
 }
 func (o *SecurityGroupUpdateOperation) Execute(ctx UpdateContext) error {
@@ -7,6 +7,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+	ot "github.com/crossplane/provider-customcomputeprovider/internal/types"
 )
 
 type TagUpdateOperation struct {
@@ -15,7 +17,7 @@ type TagUpdateOperation struct {
 
 func NewTagOperation(logger logging.Logger) *TagUpdateOperation {
 	return &TagUpdateOperation{BaseOperation: BaseOperation{
-		opType: "TAG",
+		opType: ot.TAGS.String(),
 		logger: logger,
 	}}
 }
@@ -39,7 +41,7 @@ func (o *TagUpdateOperation) Execute(ctx UpdateContext) error {
 
 	for _, tag := range ctx.Current.Tags {
 		tagKey := *tag.Key
-		if tagKey == "Name" {
+		if tagKey == "Name" || tagKey == provider.OwnerTagKey {
 			continue
 		}
 
@@ -2,36 +2,90 @@ package updater
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/crossplane-runtime/pkg/event"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
 	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	apisv1alpha1 "github.com/crossplane/provider-customcomputeprovider/apis/v1alpha1"
 	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
 	ot "github.com/crossplane/provider-customcomputeprovider/internal/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
 )
 
 type Updater interface {
 	Execute(ctx UpdateContext) error
 	GetType() string
+
+	// IsDisruptive reports whether the operation interrupts the instance,
+	// stopping or replacing it. It then waits on the maintenance window.
+	IsDisruptive(ctx UpdateContext) bool
+
+	// Preflight reports why a disruptive operation cannot be applied, before
+	// the instance is stopped for it.
+	Preflight(ctx UpdateContext) error
+}
+
+// A replacer is an operation that may replace the instance with a new one
+// rather than update it.
+type replacer interface {
+	ReplacesInstance(ctx UpdateContext) bool
 }
 
 type UpdateContext struct {
 	Context context.Context
 	Current *types.Instance
 	Desired *v1alpha1.InstanceConfig
+	Status  *v1alpha1.ComputeObservation
+	Owner   k8stypes.UID
 	Client  *provider.EC2Client
 	Logger  logging.Logger
+
+	// Resource is the Compute being updated, events about the update are
+	// recorded on it.
+	Resource *v1alpha1.Compute
+	Recorder event.Recorder
+
+	// MaintenanceWindow restricts when operations that stop the instance
+	// run, they run at any time without one.
+	MaintenanceWindow *apisv1alpha1.MaintenanceWindow
 }
 
 type BaseOperation struct {
-	opType string
-	logger logging.Logger
+	opType     string
+	disruptive bool
+	logger     logging.Logger
 }
 
 func (b *BaseOperation) GetType() string {
 	return b.opType
 }
 
+func (b *BaseOperation) IsDisruptive(_ UpdateContext) bool {
+	return b.disruptive
+}
+
+func (b *BaseOperation) Preflight(_ UpdateContext) error {
+	return nil
+}
+
+// executionOrder is the order updates are applied in.
+var executionOrder = []string{
+	ot.AMI.String(),
+	ot.NAME.String(),
+	ot.TAGS.String(),
+	ot.SECURITY_GROUPS.String(),
+	ot.IAM_PROFILE.String(),
+	ot.METADATA.String(),
+	ot.USER_DATA.String(),
+	ot.INSTANCE_TYPE.String(),
+	ot.VOLUME.String(),
+	ot.POWER_STATE.String(),
+}
+
 type UpdateOrchestrator struct {
 	operations map[string]Updater
 	logger     logging.Logger
@@ -39,11 +93,16 @@ type UpdateOrchestrator struct {
 
 func NewUpdateOrchestrator(logger logging.Logger) *UpdateOrchestrator {
 	ops := make(map[string]Updater)
+	ops[ot.AMI.String()] = NewReplacementOperation(logger)
 	ops[ot.NAME.String()] = NewNameOperation(logger)
 	ops[ot.SECURITY_GROUPS.String()] = NewSecurityGroupUpdateOperation(logger)
 	ops[ot.TAGS.String()] = NewTagOperation(logger)
 	ops[ot.INSTANCE_TYPE.String()] = NewTypeUpdateOperation(logger)
 	ops[ot.VOLUME.String()] = NewVolumeOperation(logger)
+	ops[ot.USER_DATA.String()] = NewUserDataUpdateOperation(logger)
+	ops[ot.IAM_PROFILE.String()] = NewIAMInstanceProfileOperation(logger)
+	ops[ot.METADATA.String()] = NewMetadataOptionsOperation(logger)
+	ops[ot.POWER_STATE.String()] = NewPowerStateOperation(logger)
 
 	return &UpdateOrchestrator{
 		operations: ops,
@@ -52,17 +111,52 @@ func NewUpdateOrchestrator(logger logging.Logger) *UpdateOrchestrator {
 }
 
 func (o *UpdateOrchestrator) ExecuteUpdates(updateContext UpdateContext, updates map[string]bool) error {
-	order := []string{
-		ot.NAME.String(),
-		ot.TAGS.String(),
-		ot.SECURITY_GROUPS.String(),
-		ot.INSTANCE_TYPE.String(),
-		ot.VOLUME.String(),
+	if err := o.applyRemovals(updateContext); err != nil {
+		return err
+	}
+
+	if updateContext.Status.PendingOperation != nil {
+		o.logger.Info("resuming pending operation",
+			"operations", updateContext.Status.PendingOperation.Operations,
+			"phase", updateContext.Status.PendingOperation.Phase)
+		return o.advance(updateContext)
 	}
+
+	// The maintenance window is only looked at once an operation interrupts
+	// the instance, an invalid window does not hold back the others.
+	window := &maintenanceCheck{window: updateContext.MaintenanceWindow, logger: o.logger}
+
+	// A replacement that already took the previous instance down runs to
+	// completion, one that did not waits on the maintenance window.
+	if r := updateContext.Status.Replacement; r != nil {
+		if !replacementDisrupted(r) {
+			if open, next := window.evaluate(); !open {
+				o.logger.Info("deferring instance replacement to the next maintenance window",
+					"phase", r.Phase,
+					"nextWindow", next)
+				deferUpdates(updateContext, []string{ot.AMI.String()}, next)
+				return nil
+			}
+		}
+
+		o.logger.Info("resuming instance replacement", "phase", r.Phase)
+		return o.operations[ot.AMI.String()].Execute(updateContext)
+	}
+
+	order := executionOrder
 	o.logger.Info("starting updates execution",
 		"updates_needed", updates,
 		"execution_order", order)
 
+	// Operations that need the instance stopped are collected and applied
+	// together in a single pending operation, so the instance is stopped and
+	// started only once whatever the number of disruptive changes.
+	var disruptive []string
+
+	// A replacement does not go through the stop window, it runs on its own
+	// once the online operations are applied.
+	var replacing []string
+
 	for _, opType := range order {
 		needsUpdate, exists := updates[opType]
 		if !exists || !needsUpdate {
@@ -76,6 +170,27 @@ func (o *UpdateOrchestrator) ExecuteUpdates(updateContext UpdateContext, updates
 			continue
 		}
 
+		if r, ok := op.(replacer); ok && r.ReplacesInstance(updateContext) {
+			o.logger.Info("deferring instance replacement after the online operations", "type", opType)
+			replacing = append(replacing, opType)
+			continue
+		}
+
+		if op.IsDisruptive(updateContext) {
+			o.logger.Info("deferring disruptive operation to the stop window", "type", opType)
+			disruptive = append(disruptive, opType)
+			continue
+		}
+
+		// The stop window already leaves the instance in the desired power
+		// state, unless it waits on the maintenance window.
+		if opType == ot.POWER_STATE.String() && len(disruptive) > 0 {
+			if open, _ := window.evaluate(); open {
+				o.logger.Info("power state is applied by the stop window", "type", opType)
+				continue
+			}
+		}
+
 		o.logger.Info("executing update operation",
 			"type", opType,
 			"current_state", map[string]interface{}{
@@ -83,7 +198,18 @@ func (o *UpdateOrchestrator) ExecuteUpdates(updateContext UpdateContext, updates
 				"state":       updateContext.Current.State.Name,
 			})
 
-		if err := op.Execute(updateContext); err != nil {
+		// An online operation may still have to wait on EC2, it is tracked
+		// as a pending operation that never stops the instance.
+		updateContext.Status.PendingOperation = newOnlineOperation(opType)
+
+		err := op.Execute(updateContext)
+		if errors.Is(err, ErrInProgress) {
+			o.logger.Info("operation in progress, resuming on next reconcile", "type", opType)
+			return nil
+		}
+
+		updateContext.Status.PendingOperation = nil
+		if err != nil {
 			o.logger.Info("failed to execute operation",
 				"type", opType,
 				"error", err)
@@ -98,7 +224,52 @@ func (o *UpdateOrchestrator) ExecuteUpdates(updateContext UpdateContext, updates
 			"type", opType,
 			"new_state", updateContext.Current.State.Name)
 	}
-	return nil
+
+	deferred := append(replacing, disruptive...)
+	if len(deferred) == 0 {
+		updateContext.Status.DeferredUpdates = nil
+		return nil
+	}
+
+	if open, next := window.evaluate(); !open {
+		o.logger.Info("deferring disruptive operations to the next maintenance window",
+			"operations", deferred,
+			"nextWindow", next)
+		deferUpdates(updateContext, deferred, next)
+		return nil
+	}
+	updateContext.Status.DeferredUpdates = nil
+
+	// A replacement already launches the instance from the desired
+	// configuration, the remaining updates are re-evaluated against the new
+	// instance on the next reconcile.
+	if len(replacing) > 0 {
+		o.logger.Info("executing update operation", "type", replacing[0])
+		return o.operations[replacing[0]].Execute(updateContext)
+	}
+
+	// A change EC2 is bound to refuse must not leave the instance stopped.
+	for _, opType := range disruptive {
+		if err := o.operations[opType].Preflight(updateContext); err != nil {
+			o.logger.Info("rejecting disruptive operation", "type", opType, "error", err)
+			return err
+		}
+	}
+
+	o.logger.Info("starting stop window", "operations", disruptive)
+	updateContext.Status.PendingOperation = newPendingOperation(disruptive...)
+	return o.advance(updateContext)
+}
+
+// deferUpdates records the operations waiting on the maintenance window
+// opening next.
+func deferUpdates(ctx UpdateContext, operations []string, next time.Time) {
+	deferred := &v1alpha1.DeferredUpdates{Operations: operations}
+	if !next.IsZero() {
+		nextWindow := metav1.NewTime(next)
+		deferred.NextWindow = &nextWindow
+	}
+	ctx.Status.DeferredUpdates = deferred
 }
 
 func (o *UpdateOrchestrator) refreshInstanceState(ctx *UpdateContext) error {
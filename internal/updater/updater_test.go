@@ -0,0 +1,200 @@
+package updater
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	apisv1alpha1 "github.com/crossplane/provider-customcomputeprovider/apis/v1alpha1"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider/fake"
+	ot "github.com/crossplane/provider-customcomputeprovider/internal/types"
+)
+
+// closedWindow is a maintenance window that only opens in three days.
+func closedWindow() *apisv1alpha1.MaintenanceWindow {
+	day := time.Now().UTC().AddDate(0, 0, 3).Weekday()
+	return &apisv1alpha1.MaintenanceWindow{
+		Ranges: []apisv1alpha1.MaintenanceRange{
+			{Days: []apisv1alpha1.Weekday{apisv1alpha1.Weekday(day.String())}, Start: "00:00", End: "23:59"},
+		},
+	}
+}
+
+func TestExecuteUpdatesOutsideMaintenanceWindow(t *testing.T) {
+	type want struct {
+		deferred    []string
+		replacement *v1alpha1.Replacement
+	}
+
+	cases := map[string]struct {
+		reason  string
+		window  *apisv1alpha1.MaintenanceWindow
+		desired *v1alpha1.InstanceConfig
+		status  *v1alpha1.ComputeObservation
+		updates map[string]bool
+		want    want
+	}{
+		"AMIChange": {
+			reason:  "An image change should not start a replacement outside of the maintenance window.",
+			window:  closedWindow(),
+			desired: &v1alpha1.InstanceConfig{InstanceAMI: "ami-new"},
+			status:  &v1alpha1.ComputeObservation{InstanceID: "i-current"},
+			updates: map[string]bool{ot.AMI.String(): true},
+			want:    want{deferred: []string{ot.AMI.String()}},
+		},
+		"UserDataReplacement": {
+			reason: "A user data change replacing the instance should not start outside of the maintenance window.",
+			window: closedWindow(),
+			desired: &v1alpha1.InstanceConfig{
+				UserData:             "#!/bin/sh",
+				UserDataUpdatePolicy: v1alpha1.UserDataUpdatePolicyReplace,
+			},
+			status:  &v1alpha1.ComputeObservation{InstanceID: "i-current"},
+			updates: map[string]bool{ot.USER_DATA.String(): true},
+			want:    want{deferred: []string{ot.USER_DATA.String()}},
+		},
+		"ResumedReplacement": {
+			reason:  "A replacement that has not taken the previous instance down yet should not resume outside of the maintenance window.",
+			window:  closedWindow(),
+			desired: &v1alpha1.InstanceConfig{InstanceAMI: "ami-new"},
+			status: &v1alpha1.ComputeObservation{
+				InstanceID: "i-new",
+				Replacement: &v1alpha1.Replacement{
+					PreviousInstanceID: "i-current",
+					Strategy:           v1alpha1.ReplacementStrategyCreateBeforeDestroy,
+					Phase:              phaseLaunch,
+				},
+			},
+			updates: map[string]bool{ot.AMI.String(): true},
+			want: want{
+				deferred: []string{ot.AMI.String()},
+				replacement: &v1alpha1.Replacement{
+					PreviousInstanceID: "i-current",
+					Strategy:           v1alpha1.ReplacementStrategyCreateBeforeDestroy,
+					Phase:              phaseLaunch,
+				},
+			},
+		},
+		"InvalidWindow": {
+			reason: "An invalid maintenance window should hold back a replacement rather than fail the update.",
+			window: &apisv1alpha1.MaintenanceWindow{
+				Timezone: "Mars/Olympus_Mons",
+				Ranges:   []apisv1alpha1.MaintenanceRange{{Start: "00:00", End: "23:59"}},
+			},
+			desired: &v1alpha1.InstanceConfig{InstanceAMI: "ami-new"},
+			status:  &v1alpha1.ComputeObservation{InstanceID: "i-current"},
+			updates: map[string]bool{ot.AMI.String(): true},
+			want:    want{deferred: []string{ot.AMI.String()}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			o := NewUpdateOrchestrator(logging.NewNopLogger())
+			err := o.ExecuteUpdates(UpdateContext{
+				Context: context.Background(),
+				Current: &types.Instance{
+					InstanceId: aws.String("i-current"),
+					State:      &types.InstanceState{Name: types.InstanceStateNameRunning},
+				},
+				Desired:           tc.desired,
+				Status:            tc.status,
+				Logger:            logging.NewNopLogger(),
+				MaintenanceWindow: tc.window,
+			}, tc.updates)
+			if err != nil {
+				t.Fatalf("ExecuteUpdates(...): %v", err)
+			}
+
+			got := want{replacement: tc.status.Replacement}
+			if tc.status.DeferredUpdates != nil {
+				got.deferred = tc.status.DeferredUpdates.Operations
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nExecuteUpdates(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestExecuteUpdatesGroupsDisruptiveOperations(t *testing.T) {
+	state := types.InstanceStateNameRunning
+	fakeEC2 := &fake.EC2{Outputs: map[string]fake.Output{
+		"DescribeInstances": func(input interface{}) (interface{}, error) {
+			return fake.DescribeInstances(fake.Instance("i-1", state))(input)
+		},
+		"DescribeInstanceTypes":   fake.Answer(&ec2.DescribeInstanceTypesOutput{InstanceTypes: []types.InstanceTypeInfo{{}}}),
+		"DescribeImages":          fake.Answer(&ec2.DescribeImagesOutput{}),
+		"CreateTags":              fake.Answer(&ec2.CreateTagsOutput{}),
+		"StopInstances":           fake.Answer(&ec2.StopInstancesOutput{}),
+		"StartInstances":          fake.Answer(&ec2.StartInstancesOutput{}),
+		"ModifyInstanceAttribute": fake.Answer(&ec2.ModifyInstanceAttributeOutput{}),
+	}}
+	status := &v1alpha1.ComputeObservation{InstanceID: "i-1"}
+	updates := map[string]bool{
+		ot.TAGS.String():          true,
+		ot.USER_DATA.String():     true,
+		ot.INSTANCE_TYPE.String(): true,
+		ot.POWER_STATE.String():   true,
+	}
+
+	reconcile := func() {
+		t.Helper()
+		current := fake.Instance("i-1", state)
+		err := NewUpdateOrchestrator(logging.NewNopLogger()).ExecuteUpdates(UpdateContext{
+			Context: context.Background(),
+			Current: &current,
+			Desired: &v1alpha1.InstanceConfig{
+				InstanceType:         "m5.xlarge",
+				UserData:             "#!/bin/sh",
+				UserDataUpdatePolicy: v1alpha1.UserDataUpdatePolicyStopStart,
+				InstanceTags:         map[string]string{"team": "infra"},
+			},
+			Status: status,
+			Client: &provider.EC2Client{Client: fakeEC2.Client()},
+			Logger: logging.NewNopLogger(),
+		}, updates)
+		if err != nil {
+			t.Fatalf("ExecuteUpdates(...): %v", err)
+		}
+	}
+
+	// The online operations are applied first, then the instance is stopped
+	// once for both disruptive operations, the power state being left to the
+	// stop window.
+	reconcile()
+	wantPending := &v1alpha1.PendingOperation{
+		Operations: []string{ot.USER_DATA.String(), ot.INSTANCE_TYPE.String()},
+		Phase:      phaseStopping,
+	}
+	if diff := cmp.Diff(wantPending, status.PendingOperation,
+		cmpopts.IgnoreFields(v1alpha1.PendingOperation{}, "StartedAt")); diff != "" {
+		t.Errorf("\nThe disruptive operations should share a single stop window.\nExecuteUpdates(...): -want, +got:\n%s\n", diff)
+	}
+	wantCalls := []string{"CreateTags", "DescribeInstances", "DescribeInstanceTypes", "DescribeImages", "StopInstances"}
+	if diff := cmp.Diff(wantCalls, fakeEC2.Calls); diff != "" {
+		t.Errorf("\nThe instance should be stopped once after the online operations.\nExecuteUpdates(...): -want, +got:\n%s\n", diff)
+	}
+
+	// Once stopped, both operations are applied before a single start.
+	state = types.InstanceStateNameStopped
+	fakeEC2.Calls = nil
+	reconcile()
+	wantCalls = []string{"ModifyInstanceAttribute", "ModifyInstanceAttribute", "DescribeInstances", "StartInstances"}
+	if diff := cmp.Diff(wantCalls, fakeEC2.Calls); diff != "" {
+		t.Errorf("\nThe stopped instance should be modified for each operation and started once.\nExecuteUpdates(...): -want, +got:\n%s\n", diff)
+	}
+	if status.PendingOperation.Phase != phaseStarting {
+		t.Errorf("\nThe instance should be starting once the operations are applied.\nExecuteUpdates(...): want phase %s, got %s\n",
+			phaseStarting, status.PendingOperation.Phase)
+	}
+}
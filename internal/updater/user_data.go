@@ -0,0 +1,45 @@
+package updater
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	ot "github.com/crossplane/provider-customcomputeprovider/internal/types"
+)
+
+// UserDataUpdateOperation applies a user data change. EC2 only accepts it
+// while the instance is stopped, unless the policy asks for a replacement.
+type UserDataUpdateOperation struct {
+	BaseOperation
+	replacement *ReplacementOperation
+}
+
+func NewUserDataUpdateOperation(logger logging.Logger) *UserDataUpdateOperation {
+	return &UserDataUpdateOperation{
+		BaseOperation: BaseOperation{opType: ot.USER_DATA.String(), disruptive: true, logger: logger},
+		replacement:   NewReplacementOperation(logger),
+	}
+}
+
+// ReplacesInstance reports whether the user data is applied by replacing the
+// instance rather than restarting it.
+func (u *UserDataUpdateOperation) ReplacesInstance(ctx UpdateContext) bool {
+	return ctx.Desired.UserDataUpdatePolicy == v1alpha1.UserDataUpdatePolicyReplace
+}
+
+func (u *UserDataUpdateOperation) Execute(ctx UpdateContext) error {
+	if ctx.Desired.UserDataUpdatePolicy == v1alpha1.UserDataUpdatePolicyReplace {
+		u.logger.Info("user data changed, replacing instance", "instance", *ctx.Current.InstanceId)
+		return u.replacement.Execute(ctx)
+	}
+
+	_, err := ctx.Client.Client.ModifyInstanceAttribute(ctx.Context, &ec2.ModifyInstanceAttributeInput{
+		InstanceId: ctx.Current.InstanceId,
+		UserData: &types.BlobAttributeValue{
+			Value: []byte(ctx.Desired.UserData),
+		},
+	})
+
+	return err
+}
@@ -0,0 +1,78 @@
+package updater
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider/fake"
+)
+
+func TestUserDataUpdateExecute(t *testing.T) {
+	type want struct {
+		userData    string
+		replacement bool
+		calls       []string
+	}
+
+	cases := map[string]struct {
+		reason string
+		policy v1alpha1.UserDataUpdatePolicy
+		want   want
+	}{
+		"Default": {
+			reason: "User data should be modified on the stopped instance when no policy is set.",
+			want:   want{userData: "#!/bin/sh", calls: []string{"ModifyInstanceAttribute"}},
+		},
+		"StopStart": {
+			reason: "User data should be modified on the stopped instance.",
+			policy: v1alpha1.UserDataUpdatePolicyStopStart,
+			want:   want{userData: "#!/bin/sh", calls: []string{"ModifyInstanceAttribute"}},
+		},
+		"Replace": {
+			reason: "User data should be applied by launching a replacement instance.",
+			policy: v1alpha1.UserDataUpdatePolicyReplace,
+			want:   want{replacement: true, calls: []string{"RunInstances", "DescribeInstances"}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var userData string
+			fakeEC2 := &fake.EC2{Outputs: map[string]fake.Output{
+				"ModifyInstanceAttribute": func(input interface{}) (interface{}, error) {
+					userData = string(input.(*ec2.ModifyInstanceAttributeInput).UserData.Value)
+					return &ec2.ModifyInstanceAttributeOutput{}, nil
+				},
+				"RunInstances": fake.Answer(&ec2.RunInstancesOutput{
+					Instances: []types.Instance{fake.Instance("i-new", types.InstanceStateNamePending)},
+				}),
+				"DescribeInstances": fake.DescribeInstances(fake.Instance("i-new", types.InstanceStateNamePending)),
+			}}
+			current := fake.Instance("i-1", types.InstanceStateNameStopped)
+			status := &v1alpha1.ComputeObservation{InstanceID: "i-1"}
+
+			err := NewUserDataUpdateOperation(logging.NewNopLogger()).Execute(UpdateContext{
+				Context: context.Background(),
+				Current: &current,
+				Desired: &v1alpha1.InstanceConfig{UserData: "#!/bin/sh", UserDataUpdatePolicy: tc.policy},
+				Status:  status,
+				Client:  &provider.EC2Client{Client: fakeEC2.Client()},
+			})
+			if err != nil {
+				t.Fatalf("Execute(...): %v", err)
+			}
+
+			got := want{userData: userData, replacement: status.Replacement != nil, calls: fakeEC2.Calls}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{})); diff != "" {
+				t.Errorf("\n%s\nExecute(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
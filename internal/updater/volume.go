@@ -1,15 +1,17 @@
 package updater
 
 import (
-	"context"
-	"time"
+	"errors"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	"github.com/crossplane/provider-customcomputeprovider/internal/commands/volume"
 	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
 	"github.com/crossplane/provider-customcomputeprovider/internal/shared"
+	ot "github.com/crossplane/provider-customcomputeprovider/internal/types"
 )
 
 type VolumeUpdateOperation struct {
@@ -18,89 +20,157 @@ type VolumeUpdateOperation struct {
 
 func NewVolumeOperation(logger logging.Logger) *VolumeUpdateOperation {
 	return &VolumeUpdateOperation{
-		BaseOperation: BaseOperation{opType: "VOLUME", logger: logger},
+		BaseOperation: BaseOperation{opType: ot.VOLUME.String(), logger: logger},
 	}
 }
 
+// IsDisruptive reports whether any of the volume commands needs the instance
+// stopped. Size and type changes are applied to attached volumes online.
+func (o *VolumeUpdateOperation) IsDisruptive(ctx UpdateContext) bool {
+	_, commands, err := o.analyze(ctx)
+	if err != nil {
+		o.logger.Info("failed to analyze volume changes, assuming a stop is required", "error", err)
+		return true
+	}
+
+	for _, cmd := range commands {
+		if cmd.RequiresStop() {
+			return true
+		}
+	}
+	return false
+}
+
+// Execute runs the volume commands. It returns ErrInProgress while a volume
+// is being created or modified, volumes created by a previous call are picked
+// up from the pending operation so a command waiting on EC2 resumes instead of
+// creating a second volume.
 func (o *VolumeUpdateOperation) Execute(ctx UpdateContext) error {
-	output, err := ctx.Client.Client.DescribeVolumes(ctx.Context, &ec2.DescribeVolumesInput{
-		Filters: []types.Filter{
-			{Name: aws.String("attachment.instance-id"), Values: []string{*ctx.Current.InstanceId}},
-		},
-	})
+	volumeIDs, commands, err := o.analyze(ctx)
+	if err != nil {
+		return err
+	}
 
+	modifying, err := o.modifying(ctx, volumeIDs)
 	if err != nil {
 		return err
 	}
 
-	analyzer := shared.NewCommandAnalyzer()
-	state := analyzer.BuildVolumeState(output, ctx.Current)
-	state.Desired = ctx.Desired.Storage
-	commands := analyzer.AnalyzeChanges(state)
+	if len(modifying) > 0 {
+		o.logger.Info("waiting for volume modifications to reach optimizing", "volumes", modifying)
+		return ErrInProgress
+	}
 
-	if len(commands) > 0 {
-		turnOffChannel := make(chan struct{})
+	pending := ctx.Status.PendingOperation
+	modified := false
+
+	for _, cmd := range commands {
+		create, isCreate := cmd.(*volume.CreateVolumeCommand)
+		if isCreate && pending != nil && create.VolumeId == "" {
+			create.VolumeId = pending.CreatedVolumes[create.DeviceName]
+		}
+		if isCreate {
+			create.Owner = ctx.Owner
+			create.ClientToken = volumeClientToken(ctx, create)
+		}
 
-		go o.turnOff(ctx.Context, ctx.Client, turnOffChannel, *ctx.Current.InstanceId)
+		if detach, isDetach := cmd.(*volume.DetachVolumeCommand); isDetach {
+			trackRemoval(ctx.Status, v1alpha1.VolumeRemoval{
+				VolumeID:      detach.VolumeId,
+				DeviceName:    detach.DeviceName,
+				RemovalPolicy: detach.RemovalPolicy,
+				Snapshot:      detach.Snapshot,
+			})
+		}
 
-		<-turnOffChannel
+		err := cmd.Run(ctx.Context, ctx.Client)
 
-		for _, cmd := range commands {
-			if err := cmd.Run(ctx.Context, ctx.Client); err != nil {
-				return err
+		if isCreate && pending != nil && create.VolumeId != "" {
+			if pending.CreatedVolumes == nil {
+				pending.CreatedVolumes = make(map[string]string)
+			}
+			pending.CreatedVolumes[create.DeviceName] = create.VolumeId
+			if err == nil {
+				delete(pending.CreatedVolumes, create.DeviceName)
 			}
 		}
 
-		return o.turnOn(ctx.Context, ctx.Client, *ctx.Current.InstanceId)
+		if errors.Is(err, volume.ErrPending) {
+			o.logger.Info("waiting on volume command", "type", cmd.GetType())
+			return ErrInProgress
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if _, isModify := cmd.(*volume.ModifyVolumeCommand); isModify {
+			modified = true
+		}
+	}
+
+	if modified {
+		return ErrInProgress
 	}
 
 	return nil
 }
-func (o *VolumeUpdateOperation) turnOn(ctx context.Context, c *provider.EC2Client, instanceID string) error {
-	_, err := c.Client.StartInstances(ctx, &ec2.StartInstancesInput{
-		InstanceIds: []string{instanceID},
-	})
 
-	return err
+// volumeClientToken derives the idempotency token of a volume creation. A
+// creation whose volume ID was lost with a failed status update is retried
+// with the same token, and so returns the volume already created. The volume
+// recorded for the entry, the one a shrink replaces, tells apart successive
+// volumes of a device.
+func volumeClientToken(ctx UpdateContext, create *volume.CreateVolumeCommand) string {
+	replaced := ctx.Status.StorageVolumes[create.DeviceName].VolumeID
+	return provider.ClientToken(ctx.Owner, create.InstanceId+"/"+create.DeviceName+"/"+replaced)
 }
 
-func (o *VolumeUpdateOperation) turnOff(ctx context.Context, c *provider.EC2Client, turnOffChannel chan<- struct{}, instanceID string) error {
-	defer func() { turnOffChannel <- struct{}{} }()
-
-	_, err := c.Client.StopInstances(ctx, &ec2.StopInstancesInput{
-		InstanceIds: []string{instanceID},
+func (o *VolumeUpdateOperation) analyze(ctx UpdateContext) ([]string, []volume.VolumeCommand, error) {
+	output, err := ctx.Client.Client.DescribeVolumes(ctx.Context, &ec2.DescribeVolumesInput{
+		Filters: []types.Filter{
+			{Name: aws.String("attachment.instance-id"), Values: []string{*ctx.Current.InstanceId}},
+		},
 	})
 
 	if err != nil {
-		o.logger.Info("failed to stop instance", "instance", instanceID, "error", err)
-		return err
+		return nil, nil, err
 	}
 
-	ticker := time.NewTicker(time.Second * 10)
-
-	for {
-		<-ticker.C
-
-		output, err := c.Client.DescribeInstanceStatus(ctx, &ec2.DescribeInstanceStatusInput{
-			InstanceIds:         []string{instanceID},
-			IncludeAllInstances: aws.Bool(true),
-		})
+	volumeIDs := make([]string, 0, len(output.Volumes))
+	for _, v := range output.Volumes {
+		volumeIDs = append(volumeIDs, *v.VolumeId)
+	}
 
-		if err != nil {
-			o.logger.Debug("failed to get instance status", "error", err)
-			return err
-		}
+	analyzer := shared.NewCommandAnalyzer()
+	state := analyzer.BuildVolumeState(output, ctx.Current)
+	state.Desired = ctx.Desired.Storage
+	state.Tracked = ctx.Status.StorageVolumes
 
-		instanceState := output.InstanceStatuses[0].InstanceState.Name
-		o.logger.Info("instance state", "state", instanceState)
+	return volumeIDs, analyzer.AnalyzeChanges(state), nil
+}
 
-		if instanceState != types.InstanceStateNameStopped {
-			continue
-		}
+// modifying returns the volumes whose modification has not reached the
+// optimizing state yet. EBS only accepts a new modification from there on,
+// and the new size is usable by the guest.
+func (o *VolumeUpdateOperation) modifying(ctx UpdateContext, volumeIDs []string) ([]string, error) {
+	if len(volumeIDs) == 0 {
+		return nil, nil
+	}
 
-		break
+	output, err := ctx.Client.Client.DescribeVolumesModifications(ctx.Context, &ec2.DescribeVolumesModificationsInput{
+		Filters: []types.Filter{
+			{Name: aws.String("volume-id"), Values: volumeIDs},
+			{Name: aws.String("modification-state"), Values: []string{string(types.VolumeModificationStateModifying)}},
+		},
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	o.logger.Info("instance stopped successfully")
-	return nil
+	var modifying []string
+	for _, m := range output.VolumesModifications {
+		modifying = append(modifying, aws.ToString(m.VolumeId))
+	}
+	return modifying, nil
 }
@@ -0,0 +1,213 @@
+package updater
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/crossplane/provider-customcomputeprovider/apis/compute/v1alpha1"
+	"github.com/crossplane/provider-customcomputeprovider/internal/commands/volume"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider"
+	"github.com/crossplane/provider-customcomputeprovider/internal/provider/fake"
+	ot "github.com/crossplane/provider-customcomputeprovider/internal/types"
+)
+
+// attachedVolume is a gp3 volume attached to instance i-1.
+func attachedVolume(id, device string, size int32) types.Volume {
+	return types.Volume{
+		VolumeId:   aws.String(id),
+		Size:       aws.Int32(size),
+		VolumeType: types.VolumeTypeGp3,
+		Iops:       aws.Int32(3000),
+		Throughput: aws.Int32(125),
+		State:      types.VolumeStateInUse,
+		Attachments: []types.VolumeAttachment{{
+			InstanceId:          aws.String("i-1"),
+			Device:              aws.String(device),
+			State:               types.VolumeAttachmentStateAttached,
+			DeleteOnTermination: aws.Bool(true),
+		}},
+	}
+}
+
+// describeVolumes describes the volumes attached to the instance when asked
+// by attachment, and the created volumes when asked by ID.
+func describeVolumes(attached []types.Volume, created ...types.Volume) fake.Output {
+	return func(input interface{}) (interface{}, error) {
+		ids := input.(*ec2.DescribeVolumesInput).VolumeIds
+		if len(ids) == 0 {
+			return &ec2.DescribeVolumesOutput{Volumes: attached}, nil
+		}
+
+		output := &ec2.DescribeVolumesOutput{}
+		for _, id := range ids {
+			for _, v := range created {
+				if aws.ToString(v.VolumeId) == id {
+					output.Volumes = append(output.Volumes, v)
+				}
+			}
+		}
+		return output, nil
+	}
+}
+
+func TestVolumeUpdateExecute(t *testing.T) {
+	owner := k8stypes.UID("compute-uid")
+	root := attachedVolume("vol-root", "/dev/xvda", 8)
+	data := attachedVolume("vol-data", "/dev/sdf", 100)
+
+	creating := types.Volume{VolumeId: aws.String("vol-new"), State: types.VolumeStateCreating}
+	available := types.Volume{VolumeId: aws.String("vol-new"), State: types.VolumeStateAvailable}
+
+	type want struct {
+		err     error
+		created map[string]string
+		calls   []string
+	}
+
+	cases := map[string]struct {
+		reason    string
+		attached  []types.Volume
+		created   types.Volume
+		modifying []string
+		storage   []v1alpha1.Storage
+		pending   *v1alpha1.PendingOperation
+		want      want
+	}{
+		"Create": {
+			reason:   "A new volume should be created and recorded while EC2 creates it.",
+			attached: []types.Volume{root},
+			created:  creating,
+			storage:  []v1alpha1.Storage{{DeviceName: "/dev/sdg", DiskSize: 10, InstanceDisk: "gp3"}},
+			pending:  newOnlineOperation(ot.VOLUME.String()),
+			want: want{
+				err:     ErrInProgress,
+				created: map[string]string{"/dev/sdg": "vol-new"},
+				calls: []string{"DescribeVolumes", "DescribeVolumesModifications", "DescribeSubnets",
+					"CreateVolume", "DescribeVolumes"},
+			},
+		},
+		"ResumeCreate": {
+			reason:   "A volume created by a previous call should be attached rather than created again.",
+			attached: []types.Volume{root},
+			created:  available,
+			storage:  []v1alpha1.Storage{{DeviceName: "/dev/sdg", DiskSize: 10, InstanceDisk: "gp3"}},
+			pending: &v1alpha1.PendingOperation{
+				Operations:     []string{ot.VOLUME.String()},
+				Phase:          phaseApplying,
+				CreatedVolumes: map[string]string{"/dev/sdg": "vol-new"},
+			},
+			want: want{
+				created: map[string]string{},
+				calls: []string{"DescribeVolumes", "DescribeVolumesModifications", "DescribeVolumes",
+					"AttachVolume", "ModifyInstanceAttribute"},
+			},
+		},
+		"WaitModification": {
+			reason:    "A volume still being modified should be waited on before it is modified again.",
+			attached:  []types.Volume{root, data},
+			modifying: []string{"vol-data"},
+			storage:   []v1alpha1.Storage{{DeviceName: "/dev/sdf", DiskSize: 200, InstanceDisk: "gp3"}},
+			want: want{
+				err:   ErrInProgress,
+				calls: []string{"DescribeVolumes", "DescribeVolumesModifications"},
+			},
+		},
+		"Modify": {
+			reason:   "A larger volume should be modified online, then waited on.",
+			attached: []types.Volume{root, data},
+			storage:  []v1alpha1.Storage{{DeviceName: "/dev/sdf", DiskSize: 200, InstanceDisk: "gp3"}},
+			want: want{
+				err:   ErrInProgress,
+				calls: []string{"DescribeVolumes", "DescribeVolumesModifications", "ModifyVolume"},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var createInput *ec2.CreateVolumeInput
+			modifications := &ec2.DescribeVolumesModificationsOutput{}
+			for _, id := range tc.modifying {
+				modifications.VolumesModifications = append(modifications.VolumesModifications,
+					types.VolumeModification{VolumeId: aws.String(id), ModificationState: types.VolumeModificationStateModifying})
+			}
+
+			fakeEC2 := &fake.EC2{Outputs: map[string]fake.Output{
+				"DescribeVolumes":              describeVolumes(tc.attached, tc.created),
+				"DescribeVolumesModifications": fake.Answer(modifications),
+				"DescribeSubnets": fake.Answer(&ec2.DescribeSubnetsOutput{
+					Subnets: []types.Subnet{{AvailabilityZone: aws.String("us-east-1a")}},
+				}),
+				"CreateVolume": func(input interface{}) (interface{}, error) {
+					createInput = input.(*ec2.CreateVolumeInput)
+					return &ec2.CreateVolumeOutput{VolumeId: aws.String("vol-new")}, nil
+				},
+				"AttachVolume":            fake.Answer(&ec2.AttachVolumeOutput{}),
+				"ModifyVolume":            fake.Answer(&ec2.ModifyVolumeOutput{}),
+				"ModifyInstanceAttribute": fake.Answer(&ec2.ModifyInstanceAttributeOutput{}),
+			}}
+
+			current := fake.Instance("i-1", types.InstanceStateNameRunning)
+			current.SubnetId = aws.String("subnet-1")
+			current.RootDeviceName = aws.String("/dev/xvda")
+			status := &v1alpha1.ComputeObservation{InstanceID: "i-1", PendingOperation: tc.pending}
+
+			err := NewVolumeOperation(logging.NewNopLogger()).Execute(UpdateContext{
+				Context: context.Background(),
+				Current: &current,
+				Desired: &v1alpha1.InstanceConfig{Storage: tc.storage},
+				Status:  status,
+				Owner:   owner,
+				Client:  &provider.EC2Client{Client: fakeEC2.Client()},
+			})
+
+			got := want{err: err, calls: fakeEC2.Calls}
+			if tc.pending != nil {
+				got.created = tc.pending.CreatedVolumes
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(want{}), cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\nExecute(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+
+			if createInput == nil {
+				return
+			}
+			if diff := cmp.Diff(provider.ClientToken(owner, "i-1//dev/sdg/"), aws.ToString(createInput.ClientToken)); diff != "" {
+				t.Errorf("\n%s\nExecute(...): -want client token, +got:\n%s\n", tc.reason, diff)
+			}
+			wantTags := []types.TagSpecification{{
+				ResourceType: types.ResourceTypeVolume,
+				Tags:         []types.Tag{{Key: aws.String(provider.OwnerTagKey), Value: aws.String(string(owner))}},
+			}}
+			if diff := cmp.Diff(wantTags, createInput.TagSpecifications, cmpopts.IgnoreUnexported(types.TagSpecification{}, types.Tag{})); diff != "" {
+				t.Errorf("\n%s\nExecute(...): -want tags, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestVolumeClientToken(t *testing.T) {
+	owner := k8stypes.UID("compute-uid")
+	create := &volume.CreateVolumeCommand{InstanceId: "i-1", DeviceName: "/dev/sdf"}
+
+	first := volumeClientToken(UpdateContext{Owner: owner, Status: &v1alpha1.ComputeObservation{}}, create)
+	retried := volumeClientToken(UpdateContext{Owner: owner, Status: &v1alpha1.ComputeObservation{}}, create)
+	replacing := volumeClientToken(UpdateContext{Owner: owner, Status: &v1alpha1.ComputeObservation{
+		StorageVolumes: map[string]v1alpha1.StorageVolume{"/dev/sdf": {VolumeID: "vol-data"}},
+	}}, create)
+
+	if first != retried {
+		t.Errorf("volumeClientToken(...): a retried creation should reuse the token, got %s and %s", first, retried)
+	}
+	if first == replacing {
+		t.Errorf("volumeClientToken(...): the volume replacing a shrunk one should not reuse the token of the first volume")
+	}
+}